@@ -130,6 +130,71 @@ func TestDropAll(t *testing.T) {
 	}
 }
 
+func TestDropPredicateAndType(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "DropPredicateAndTypeWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "DropPredicateAndTypeWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			entity := TestEntity{
+				Name:        "Test Entity",
+				Description: "This is a test entity for the Drop methods",
+				CreatedAt:   time.Now(),
+			}
+
+			ctx := context.Background()
+			err := client.Insert(ctx, &entity)
+			require.NoError(t, err, "Insert should succeed")
+
+			err = client.DropPredicate(ctx, "description")
+			require.NoError(t, err, "DropPredicate should succeed")
+
+			var result TestEntity
+			err = client.Get(ctx, &result, entity.UID)
+			require.NoError(t, err, "Get should succeed")
+			require.Equal(t, "Test Entity", result.Name, "Name should be kept")
+			require.Empty(t, result.Description, "Description should be dropped")
+
+			err = client.DropPredicate(ctx, "dgraph.type")
+			require.Error(t, err, "Dropping a pre-defined predicate should fail")
+
+			err = client.DropType(ctx, "TestEntity")
+			require.NoError(t, err, "DropType should succeed")
+
+			schema, err := client.GetSchema(ctx)
+			require.NoError(t, err, "GetSchema should succeed")
+			require.NotContains(t, schema, "type TestEntity")
+
+			resp, err := client.QueryRaw(ctx, `query q($uid: string) { q(func: uid($uid)) { name } }`,
+				map[string]string{"$uid": entity.UID})
+			require.NoError(t, err, "QueryRaw should succeed")
+			require.JSONEq(t, `{"q":[{"name":"Test Entity"}]}`, string(resp), "Data should be kept after DropType")
+		})
+	}
+}
+
 type Struct1 struct {
 	UID   string   `json:"uid,omitempty"`
 	Name  string   `json:"name,omitempty" dgraph:"index=term"`
@@ -0,0 +1,177 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// aggregateBlock is the name of the query block returning the result of an aggregation
+const aggregateBlock = "aggregate"
+
+// Count returns the number of objects of type T that match the filter of the options.
+// Ordering, pagination and depth options are ignored.
+//
+// Example:
+//
+//	active, err := modusgraph.Count[User](ctx, client,
+//		modusgraph.WithFilter("eq(active, $active)", map[string]string{"$active": "true"}))
+func Count[T any](ctx context.Context, c Client, opts ...QueryOpt) (int, error) {
+	options := newQueryOptions(c, opts)
+
+	var q strings.Builder
+	writeAggregateRoot[T](&q, aggregateBlock, options)
+	q.WriteString("{\n\t\tcount(uid)\n\t}\n}")
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := aggregate(ctx, c, "Count", new(T), q.String(), options, &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// Sum returns the sum of the numeric predicate field over the objects of type T that
+// match the filter of the options, zero if there are none.
+func Sum[T any](ctx context.Context, c Client, field string, opts ...QueryOpt) (float64, error) {
+	return aggregateValue[T](ctx, c, "Sum", field, opts)
+}
+
+// Avg returns the average of the numeric predicate field over the objects of type T
+// that match the filter of the options, zero if there are none.
+func Avg[T any](ctx context.Context, c Client, field string, opts ...QueryOpt) (float64, error) {
+	return aggregateValue[T](ctx, c, "Avg", field, opts)
+}
+
+// Min returns the smallest value of the numeric predicate field among the objects of
+// type T that match the filter of the options, zero if there are none.
+func Min[T any](ctx context.Context, c Client, field string, opts ...QueryOpt) (float64, error) {
+	return aggregateValue[T](ctx, c, "Min", field, opts)
+}
+
+// Max returns the largest value of the numeric predicate field among the objects of
+// type T that match the filter of the options, zero if there are none.
+func Max[T any](ctx context.Context, c Client, field string, opts ...QueryOpt) (float64, error) {
+	return aggregateValue[T](ctx, c, "Max", field, opts)
+}
+
+// GroupBy returns the number of objects of type T that match the filter of the options
+// for each value of the predicate field. For an edge, the values are the UIDs of the
+// nodes it points to. Objects without the predicate are not counted.
+//
+// Example:
+//
+//	// the number of threads in each workspace, keyed by the UID of the workspace
+//	counts, err := modusgraph.GroupBy[Thread](ctx, client, "workspace")
+func GroupBy[T any](ctx context.Context, c Client, field string, opts ...QueryOpt) (map[string]int, error) {
+	if field == "" {
+		return nil, errors.New("field is required")
+	}
+	options := newQueryOptions(c, opts)
+
+	var q strings.Builder
+	writeAggregateRoot[T](&q, aggregateBlock, options)
+	fmt.Fprintf(&q, "@groupby(<%s>) {\n\t\tcount(uid)\n\t}\n}", field)
+
+	var result struct {
+		Groups []map[string]json.RawMessage `json:"@groupby"`
+	}
+	if err := aggregate(ctx, c, "GroupBy", new(T), q.String(), options, &result); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(result.Groups))
+	for _, group := range result.Groups {
+		var count int
+		if err := json.Unmarshal(group["count"], &count); err != nil {
+			return nil, err
+		}
+		key, err := groupKey(group[field])
+		if err != nil {
+			return nil, err
+		}
+		counts[key] += count
+	}
+	return counts, nil
+}
+
+// aggregateValue returns the result of the aggregation op, e.g. "Sum", over the values
+// of the predicate field of the objects of type T, using the DQL function of that name.
+func aggregateValue[T any](ctx context.Context, c Client, op, field string, opts []QueryOpt) (float64, error) {
+	if field == "" {
+		return 0, errors.New("field is required")
+	}
+	options := newQueryOptions(c, opts)
+	fn := strings.ToLower(op)
+
+	var q strings.Builder
+	writeAggregateRoot[T](&q, "var", options)
+	fmt.Fprintf(&q, "{\n\t\tvalues as <%s>\n\t}\n", field)
+	fmt.Fprintf(&q, "\t%s() {\n\t\tresult: %s(val(values))\n\t}\n}", aggregateBlock, fn)
+
+	var result struct {
+		Result float64 `json:"result"`
+	}
+	if err := aggregate(ctx, c, op, new(T), q.String(), options, &result); err != nil {
+		return 0, err
+	}
+	return result.Result, nil
+}
+
+// writeAggregateRoot writes the start of a query with a block named name selecting the
+// nodes of type T that match the filter of the options, up to its selection set.
+func writeAggregateRoot[T any](q *strings.Builder, name string, options queryOptions) {
+	var model T
+	if len(options.vars) > 0 {
+		q.WriteString("query ")
+		q.WriteString(varsDefinition(aggregateBlock, options.vars))
+		q.WriteString(" ")
+	}
+	fmt.Fprintf(q, "{\n\t%s(func: type(%s)) ", name, dg.GetNodeType(&model))
+	writeQueryFilter(q, options.filter)
+}
+
+// aggregate runs the aggregation query q as the operation op on obj, and decodes the
+// single result of its aggregate block into result, which is left untouched if there
+// is none.
+func aggregate(ctx context.Context, c Client, op string, obj any, q string, options queryOptions,
+	result any) error {
+	return intercept(ctx, c, op, obj, func(ctx context.Context) error {
+		data, err := queryRaw(ctx, c, q, options.vars)
+		if err != nil {
+			return err
+		}
+		var resp map[string][]json.RawMessage
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		// the aggregate block holds a single object with the results, if any node matched
+		for _, item := range resp[aggregateBlock] {
+			if err := json.Unmarshal(item, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// groupKey returns the map key of a grouped value, which is a UID for edges.
+func groupKey(raw json.RawMessage) (string, error) {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return string(raw), nil
+}
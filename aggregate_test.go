@@ -0,0 +1,129 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+type AggregateWorkspace struct {
+	Name string `json:"name,omitempty" dgraph:"index=exact"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type AggregateThread struct {
+	Title     string              `json:"title,omitempty"`
+	Status    string              `json:"status,omitempty" dgraph:"index=exact"`
+	Messages  int                 `json:"messages,omitempty" dgraph:"index=int"`
+	Score     float64             `json:"score,omitempty"`
+	Workspace *AggregateWorkspace `json:"workspace,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestAggregate(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "AggregateWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "AggregateWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			engineering := &AggregateWorkspace{Name: "Engineering"}
+			sales := &AggregateWorkspace{Name: "Sales"}
+			require.NoError(t, client.Insert(ctx, []*AggregateWorkspace{engineering, sales}))
+
+			threads := []*AggregateThread{
+				{Title: "Release", Status: "open", Messages: 10, Score: 0.5, Workspace: engineering},
+				{Title: "Outage", Status: "closed", Messages: 30, Score: 1.5, Workspace: engineering},
+				{Title: "Roadmap", Status: "open", Messages: 5, Score: 2, Workspace: engineering},
+				{Title: "Pipeline", Status: "open", Messages: 15, Score: 1, Workspace: sales},
+				{Title: "Draft"},
+			}
+			require.NoError(t, client.Insert(ctx, threads))
+
+			count, err := modusgraph.Count[AggregateThread](ctx, client)
+			require.NoError(t, err)
+			require.Equal(t, 5, count)
+
+			openFilter := modusgraph.WithFilter("eq(status, $status)", map[string]string{"$status": "open"})
+			count, err = modusgraph.Count[AggregateThread](ctx, client, openFilter)
+			require.NoError(t, err)
+			require.Equal(t, 3, count, "The filter should apply to the count")
+
+			sum, err := modusgraph.Sum[AggregateThread](ctx, client, "messages")
+			require.NoError(t, err)
+			require.Equal(t, 60.0, sum)
+			sum, err = modusgraph.Sum[AggregateThread](ctx, client, "messages", openFilter)
+			require.NoError(t, err)
+			require.Equal(t, 30.0, sum, "The filter should apply to the sum")
+
+			avg, err := modusgraph.Avg[AggregateThread](ctx, client, "score")
+			require.NoError(t, err)
+			require.InDelta(t, 1.25, avg, 1e-9)
+
+			minimum, err := modusgraph.Min[AggregateThread](ctx, client, "messages")
+			require.NoError(t, err)
+			require.Equal(t, 5.0, minimum)
+			maximum, err := modusgraph.Max[AggregateThread](ctx, client, "messages", openFilter)
+			require.NoError(t, err)
+			require.Equal(t, 15.0, maximum)
+
+			none := modusgraph.WithFilter("eq(status, $status)", map[string]string{"$status": "archived"})
+			count, err = modusgraph.Count[AggregateThread](ctx, client, none)
+			require.NoError(t, err)
+			require.Zero(t, count)
+			sum, err = modusgraph.Sum[AggregateThread](ctx, client, "messages", none)
+			require.NoError(t, err)
+			require.Zero(t, sum, "Aggregations over no objects should be zero")
+
+			byStatus, err := modusgraph.GroupBy[AggregateThread](ctx, client, "status")
+			require.NoError(t, err)
+			require.Equal(t, map[string]int{"open": 3, "closed": 1}, byStatus)
+
+			byWorkspace, err := modusgraph.GroupBy[AggregateThread](ctx, client, "workspace", openFilter)
+			require.NoError(t, err)
+			require.Equal(t, map[string]int{engineering.UID: 2, sales.UID: 1}, byWorkspace,
+				"Edges should be grouped by the UID of their target")
+
+			byMessages, err := modusgraph.GroupBy[AggregateThread](ctx, client, "messages", openFilter)
+			require.NoError(t, err)
+			require.Equal(t, map[string]int{"5": 1, "10": 1, "15": 1}, byMessages)
+
+			_, err = modusgraph.GroupBy[AggregateThread](ctx, client, "")
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,341 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+const (
+	// autoCreateTimeTag is the dgraph tag option of a time field set when its node is created
+	autoCreateTimeTag = "autoCreateTime"
+	// autoUpdateTimeTag is the dgraph tag option of a time field set whenever its node is written
+	autoUpdateTimeTag = "autoUpdateTime"
+	// versionTag is the dgraph tag option of an integer field holding the version of its node,
+	// which updates compare and increment
+	versionTag = "version"
+)
+
+// autoFields holds the fields of a struct type that the client fills in.
+//
+// createTime: the time fields set when a node is created.
+// updateTime: the time fields set whenever a node is written.
+// version: the version field, if any.
+type autoFields struct {
+	createTime []reflect.StructField
+	updateTime []reflect.StructField
+	version    *reflect.StructField
+}
+
+// typeAutoFields caches the auto fields of struct types, by type
+var typeAutoFields sync.Map
+
+// autoFieldsOf returns the auto fields of the type of obj, which may be a struct, a pointer
+// to one, or a slice of them.
+func autoFieldsOf(obj any) (*autoFields, error) {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if cached, ok := typeAutoFields.Load(t); ok {
+		return cached.(*autoFields), nil
+	}
+	af := &autoFields{}
+	if t.Kind() == reflect.Struct {
+		for _, field := range reflect.VisibleFields(t) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			options := tagOptions(field.Tag.Get("dgraph"))
+			if slices.Contains(options, autoCreateTimeTag) || slices.Contains(options, autoUpdateTimeTag) {
+				if field.Type != reflect.TypeFor[time.Time]() && field.Type != reflect.TypeFor[*time.Time]() {
+					return nil, fmt.Errorf("field %s.%s must be a time.Time to be set automatically",
+						t.Name(), field.Name)
+				}
+			}
+			if slices.Contains(options, autoCreateTimeTag) {
+				af.createTime = append(af.createTime, field)
+			}
+			if slices.Contains(options, autoUpdateTimeTag) {
+				af.updateTime = append(af.updateTime, field)
+			}
+			if slices.Contains(options, versionTag) {
+				if af.version != nil {
+					return nil, fmt.Errorf("type %s has more than one version field", t.Name())
+				}
+				if _, ok := numberValue(reflect.Zero(field.Type)); !ok || field.Type.Kind() == reflect.Float32 ||
+					field.Type.Kind() == reflect.Float64 {
+					return nil, fmt.Errorf("version field %s.%s must be an integer", t.Name(), field.Name)
+				}
+				af.version = &field
+			}
+		}
+	}
+	cached, _ := typeAutoFields.LoadOrStore(t, af)
+	return cached.(*autoFields), nil
+}
+
+// any reports whether the type has auto fields.
+func (af *autoFields) any() bool {
+	return len(af.createTime) > 0 || len(af.updateTime) > 0 || af.version != nil
+}
+
+// predicates returns the predicates of the fields that an update writes.
+func (af *autoFields) predicates() []string {
+	var predicates []string
+	for _, field := range af.updateTime {
+		predicates = append(predicates, predicateName(field))
+	}
+	if af.version != nil {
+		predicates = append(predicates, predicateName(*af.version))
+	}
+	return predicates
+}
+
+// stamp sets the update time fields of each object of obj to now. If created is true,
+// the create time fields that are not set yet are set to now as well, and the version
+// field to 1.
+func (af *autoFields) stamp(obj any, created bool) {
+	now := time.Now().UTC()
+	_ = eachObject(obj, func(v reflect.Value) error {
+		for _, field := range af.updateTime {
+			setTime(v.FieldByIndex(field.Index), now)
+		}
+		if !created {
+			return nil
+		}
+		for _, field := range af.createTime {
+			if f := v.FieldByIndex(field.Index); isEmpty(f) {
+				setTime(f, now)
+			}
+		}
+		if af.version != nil {
+			af.setVersion(v, 1)
+		}
+		return nil
+	})
+}
+
+// setTime sets the time.Time or *time.Time field f to t.
+func setTime(f reflect.Value, t time.Time) {
+	if f.Kind() == reflect.Ptr {
+		f.Set(reflect.ValueOf(&t))
+		return
+	}
+	f.Set(reflect.ValueOf(t))
+}
+
+// versionOf returns the version of the struct v.
+func (af *autoFields) versionOf(v reflect.Value) int64 {
+	n, _ := numberValue(v.FieldByIndex(af.version.Index))
+	return int64(n)
+}
+
+// setVersion sets the version of the struct v.
+func (af *autoFields) setVersion(v reflect.Value, version int64) {
+	f := v.FieldByIndex(af.version.Index)
+	if f.CanInt() {
+		f.SetInt(version)
+	} else {
+		f.SetUint(uint64(version))
+	}
+}
+
+// eachObject calls fn with the struct value of obj, or of each object of obj if it is a
+// slice or a pointer to a slice. It stops at the first error.
+func eachObject(obj any, fn func(v reflect.Value) error) error {
+	val := reflect.ValueOf(obj)
+	if val.Kind() == reflect.Ptr && val.Elem().Kind() == reflect.Slice {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice {
+		return fn(reflect.Indirect(val))
+	}
+	for i := range val.Len() {
+		if err := fn(reflect.Indirect(val.Index(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionQuery returns the upsert block query matching the node with the given UID if it
+// is at version, and its variables. A version of 0 matches nodes without a version. The
+// version stored for the node is returned in the stored block.
+func versionQuery(uid, predicate string, version int64) (string, map[string]string) {
+	vars := map[string]string{"$uid": uid}
+	decls := "$uid: string"
+	filter := fmt.Sprintf("NOT has(<%s>)", predicate)
+	if version != 0 {
+		vars["$version"] = strconv.FormatInt(version, 10)
+		decls += ", $version: int"
+		filter = fmt.Sprintf("eq(<%s>, $version)", predicate)
+	}
+	query := fmt.Sprintf("query q(%s) {\n  q(func: uid($uid)) @filter(%s) {\n    %s as uid\n  }\n"+
+		"  stored(func: uid($uid)) {\n    version: <%s>\n  }\n}\n", decls, filter, upsertVar, predicate)
+	return query, vars
+}
+
+// versionCond is the condition of the mutation of an upsert block built with versionQuery
+var versionCond = fmt.Sprintf("@if(eq(len(%s), 1))", upsertVar)
+
+// checkVersion returns a *StaleVersionError if the upsert block built with versionQuery
+// for obj at version, whose results are data, did not match its node.
+func checkVersion(data []byte, obj any, uid string, version int64) error {
+	var result struct {
+		Q      []uidNode `json:"q"`
+		Stored []struct {
+			Version int64 `json:"version"`
+		} `json:"stored"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	if len(result.Q) > 0 {
+		return nil
+	}
+	staleErr := &StaleVersionError{NodeType: getNodeType(obj), UID: uid, Version: version}
+	if len(result.Stored) > 0 {
+		staleErr.Stored = result.Stored[0].Version
+	}
+	return staleErr
+}
+
+// updateVersioned updates each object of obj in txn if the version stored for it is the
+// version of the object, and increments the version of the objects. If one of them has
+// another version, nothing is written and a *StaleVersionError is returned. If commitNow
+// is true, txn is committed or discarded.
+func (c client) updateVersioned(ctx context.Context, txn *dg.TxnContext, commitNow bool, obj any,
+	af *autoFields) error {
+	err := c.checkVersions(ctx, txn, obj, af)
+	if err == nil {
+		err = eachObject(obj, func(v reflect.Value) error {
+			return c.compareAndSet(ctx, txn, v.Addr().Interface(), af)
+		})
+	}
+	if !commitNow {
+		return err
+	}
+	if err != nil {
+		_ = txn.Txn().Discard(ctx)
+		return err
+	}
+	return txn.Txn().Commit(ctx)
+}
+
+// checkVersions returns a *StaleVersionError for the first object of obj whose version is
+// not the one stored for its node in txn, or the one an earlier object for the same node
+// is written with, so that none of the objects are written if one of them is stale.
+func (c client) checkVersions(ctx context.Context, txn *dg.TxnContext, obj any, af *autoFields) error {
+	type versioned struct {
+		obj     any
+		uid     string
+		version int64
+	}
+	var objects []versioned
+	var uids []string
+	err := eachObject(obj, func(v reflect.Value) error {
+		o := versioned{obj: v.Addr().Interface(), version: af.versionOf(v)}
+		if o.uid = getUIDValue(o.obj); o.uid == "" {
+			return fmt.Errorf("object must have a UID to be updated")
+		}
+		objects = append(objects, o)
+		uids = append(uids, o.uid)
+		return nil
+	})
+	if err != nil || len(objects) < 2 {
+		// compareAndSet checks the version of a single object
+		return err
+	}
+
+	query := fmt.Sprintf("query q($uids: string) {\n  stored(func: uid($uids)) {\n    uid\n    version: <%s>\n  }\n}\n",
+		predicateName(*af.version))
+	resp, err := txn.Txn().QueryWithVars(ctx, query, map[string]string{"$uids": uidList(uids)})
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Stored []struct {
+			UID     string `json:"uid"`
+			Version int64  `json:"version"`
+		} `json:"stored"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+		return err
+	}
+	stored := make(map[string]int64, len(result.Stored))
+	for _, node := range result.Stored {
+		stored[node.UID] = node.Version
+	}
+	for _, o := range objects {
+		if o.version != stored[o.uid] {
+			return &StaleVersionError{NodeType: getNodeType(o.obj), UID: o.uid, Version: o.version,
+				Stored: stored[o.uid]}
+		}
+		stored[o.uid] = o.version + 1
+	}
+	return nil
+}
+
+// compareAndSet writes obj in txn if the version stored for its node is its version,
+// incrementing it.
+func (c client) compareAndSet(ctx context.Context, txn *dg.TxnContext, obj any, af *autoFields) error {
+	uid := getUIDValue(obj)
+	if uid == "" {
+		return fmt.Errorf("object must have a UID to be updated")
+	}
+	v := reflect.ValueOf(obj).Elem()
+	version := af.versionOf(v)
+	query, vars := versionQuery(uid, predicateName(*af.version), version)
+	af.setVersion(v, version+1)
+	data, err := c.upsertBlock(ctx, txn, false, obj, query, vars, versionCond)
+	if err == nil {
+		err = checkVersion(data, obj, uid, version)
+	}
+	if err != nil {
+		// the mutation was not applied, so no node was created
+		clearBlankUIDs(obj)
+		v.FieldByName("UID").SetString(uid)
+		af.setVersion(v, version)
+	}
+	return err
+}
+
+// updateFieldsVersioned writes setJSON, the fields of obj to update, in tx if the version
+// stored for the node of obj is version, or in a new transaction committed once it is
+// written if tx is nil.
+func (c client) updateFieldsVersioned(ctx context.Context, tx *dg.TxnContext, obj any, setJSON []byte,
+	version int64, af *autoFields) error {
+	uid := getUIDValue(obj)
+	query, vars := versionQuery(uid, predicateName(*af.version), version)
+	req := &api.Request{
+		Query:     query,
+		Vars:      vars,
+		Mutations: []*api.Mutation{{SetJson: setJSON, Cond: versionCond}},
+		CommitNow: tx == nil,
+	}
+	return c.withTxn(ctx, tx, func(txn *dg.TxnContext) error {
+		resp, err := txn.Txn().Do(ctx, req)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(resp.GetJson(), obj, uid, version); err != nil {
+			return err
+		}
+		c.logger.V(2).Info("UpdateFields successful", "uid", uid, "version", version+1)
+		return nil
+	})
+}
@@ -0,0 +1,214 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+type VersionedDoc struct {
+	Title     string    `json:"title,omitempty" dgraph:"index=exact upsert"`
+	Body      string    `json:"body,omitempty"`
+	CreatedAt time.Time `json:"createdAt,omitempty" dgraph:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty" dgraph:"autoUpdateTime"`
+	Version   int       `json:"version,omitempty" dgraph:"version"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type TimestampedNote struct {
+	Key       string     `json:"key,omitempty" dgraph:"index=exact upsert"`
+	Text      string     `json:"text,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" dgraph:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updatedAt,omitempty" dgraph:"autoUpdateTime"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientAutoFields(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "AutoFieldsWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "AutoFieldsWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+			ctx := context.Background()
+
+			doc := VersionedDoc{Title: "draft"}
+			require.NoError(t, client.Insert(ctx, &doc))
+			require.Equal(t, 1, doc.Version, "Inserted objects should start at version 1")
+			require.False(t, doc.CreatedAt.IsZero())
+			require.Equal(t, doc.CreatedAt, doc.UpdatedAt)
+			created := doc.CreatedAt
+
+			// two editors read the same version
+			var first, second VersionedDoc
+			require.NoError(t, client.Get(ctx, &first, doc.UID))
+			require.NoError(t, client.Get(ctx, &second, doc.UID))
+			require.Equal(t, 1, first.Version)
+
+			first.Body = "first edit"
+			require.NoError(t, client.Update(ctx, &first))
+			require.Equal(t, 2, first.Version)
+			require.True(t, first.UpdatedAt.After(created))
+
+			second.Body = "second edit"
+			err := client.Update(ctx, &second)
+			require.ErrorIs(t, err, modusgraph.ErrStaleVersion)
+			var staleErr *modusgraph.StaleVersionError
+			require.True(t, errors.As(err, &staleErr))
+			require.Equal(t, int64(1), staleErr.Version)
+			require.Equal(t, int64(2), staleErr.Stored)
+			require.Equal(t, 1, second.Version, "A stale object should keep its version")
+			require.Equal(t, doc.UID, second.UID)
+
+			second.Body = "fields edit"
+			require.ErrorIs(t, client.UpdateFields(ctx, &second, "body"), modusgraph.ErrStaleVersion)
+			first.Body = "fields edit"
+			require.NoError(t, client.UpdateFields(ctx, &first, "body"))
+			require.Equal(t, 3, first.Version)
+
+			var fetched VersionedDoc
+			require.NoError(t, client.Get(ctx, &fetched, doc.UID))
+			require.Equal(t, "fields edit", fetched.Body)
+			require.Equal(t, 3, fetched.Version)
+			// datetimes are stored to the second
+			require.WithinDuration(t, created, fetched.CreatedAt, time.Second,
+				"Updates should keep the creation time")
+			require.WithinDuration(t, first.UpdatedAt, fetched.UpdatedAt, time.Second)
+
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err)
+			defer func() { _ = tx.Discard(ctx) }()
+			require.ErrorIs(t, tx.Update(ctx, &second), modusgraph.ErrStaleVersion)
+			require.NoError(t, tx.Update(ctx, &first))
+			require.NoError(t, tx.Commit(ctx))
+			require.Equal(t, 4, first.Version)
+
+			upserted := VersionedDoc{Title: "published", Body: "new"}
+			require.NoError(t, client.Upsert(ctx, &upserted))
+			require.Equal(t, 1, upserted.Version)
+			require.False(t, upserted.CreatedAt.IsZero())
+			require.ErrorIs(t, client.Upsert(ctx, &VersionedDoc{Title: "published", Body: "blind write"}),
+				modusgraph.ErrStaleVersion, "Upserting an existing node should compare its version")
+			upserted.UID = ""
+			upserted.Body = "updated"
+			require.NoError(t, client.Upsert(ctx, &upserted))
+			require.Equal(t, 2, upserted.Version)
+			require.NoError(t, client.Get(ctx, &fetched, upserted.UID))
+			require.Equal(t, "updated", fetched.Body)
+
+			noteCreated := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			note := TimestampedNote{Key: "a", Text: "first", CreatedAt: &noteCreated}
+			require.NoError(t, client.Upsert(ctx, &note))
+			require.Equal(t, noteCreated, *note.CreatedAt, "A creation time already set should be kept")
+			require.NoError(t, client.Upsert(ctx, &TimestampedNote{Key: "a", Text: "second"}))
+			var fetchedNote TimestampedNote
+			require.NoError(t, client.Get(ctx, &fetchedNote, note.UID))
+			require.Equal(t, "second", fetchedNote.Text)
+			require.True(t, noteCreated.Equal(*fetchedNote.CreatedAt),
+				"Upserting an existing node should keep its creation time")
+			require.WithinDuration(t, time.Now(), fetchedNote.UpdatedAt, time.Minute)
+		})
+	}
+}
+
+func TestClientAutoFieldsStaleSlice(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "AutoFieldsStaleSliceWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "AutoFieldsStaleSliceWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+			ctx := context.Background()
+
+			docs := []*VersionedDoc{{Title: "a"}, {Title: "b"}, {Title: "c"}}
+			require.NoError(t, client.Insert(ctx, docs))
+			stale := *docs[2]
+			docs[2].Body = "concurrent edit"
+			require.NoError(t, client.Update(ctx, docs[2]))
+
+			edits := []*VersionedDoc{docs[0], docs[1], &stale}
+			for _, doc := range edits {
+				doc.Body = "batch edit"
+			}
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err)
+			defer func() { _ = tx.Discard(ctx) }()
+			err = tx.Update(ctx, edits)
+			require.ErrorIs(t, err, modusgraph.ErrStaleVersion)
+			var staleErr *modusgraph.StaleVersionError
+			require.True(t, errors.As(err, &staleErr))
+			require.Equal(t, stale.UID, staleErr.UID)
+			require.Equal(t, int64(2), staleErr.Stored)
+			require.NoError(t, tx.Commit(ctx))
+
+			for i, doc := range edits {
+				require.Equal(t, 1, doc.Version, "The objects of a stale slice should keep their versions")
+				require.Equal(t, docs[i].UID, doc.UID)
+			}
+			for _, doc := range docs[:2] {
+				var fetched VersionedDoc
+				require.NoError(t, client.Get(ctx, &fetched, doc.UID))
+				require.Empty(t, fetched.Body, "Nothing should be written when an object is stale")
+				require.Equal(t, 1, fetched.Version)
+			}
+
+			stale.Version = 2
+			require.NoError(t, client.Update(ctx, edits))
+			require.Equal(t, []int{2, 2, 3}, []int{edits[0].Version, edits[1].Version, edits[2].Version})
+		})
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// defaultBatchSize is the number of objects InsertBatch sends per mutation by default
+	defaultBatchSize = 1000
+	// defaultBatchConcurrency is the number of batches InsertBatch runs at once by default
+	defaultBatchConcurrency = 4
+)
+
+// batchOptions holds the options of InsertBatch.
+//
+// batchSize: the number of objects sent per mutation.
+// concurrency: the maximum number of batches inserted at once.
+// progress: called after each batch with the number of objects processed so far.
+type batchOptions struct {
+	batchSize   int
+	concurrency int
+	progress    func(done, total int)
+}
+
+// BatchOpt is a function that configures InsertBatch.
+type BatchOpt func(*batchOptions)
+
+// WithBatchSize sets the number of objects InsertBatch sends per mutation. It defaults to 1000.
+func WithBatchSize(size int) BatchOpt {
+	return func(o *batchOptions) {
+		o.batchSize = size
+	}
+}
+
+// WithConcurrency sets the maximum number of batches InsertBatch inserts at once. It defaults to 4.
+func WithConcurrency(n int) BatchOpt {
+	return func(o *batchOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithProgress sets a function InsertBatch calls after each batch, with the number of
+// objects processed so far, whether inserted or failed, and the total number of objects.
+// Calls are never concurrent.
+func WithProgress(fn func(done, total int)) BatchOpt {
+	return func(o *batchOptions) {
+		o.progress = fn
+	}
+}
+
+// InsertBatch implements inserting a large slice of objects in batches.
+func (c client) InsertBatch(ctx context.Context, objs any, opts ...BatchOpt) error {
+	return c.intercept(ctx, "InsertBatch", objs, func(ctx context.Context) error {
+		return c.insertBatch(ctx, objs, opts)
+	})
+}
+
+func (c client) insertBatch(ctx context.Context, objs any, opts []BatchOpt) error {
+	options := batchOptions{
+		batchSize:   defaultBatchSize,
+		concurrency: defaultBatchConcurrency,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.batchSize <= 0 {
+		return errors.New("batch size must be greater than zero")
+	}
+	if options.concurrency <= 0 {
+		return errors.New("concurrency must be greater than zero")
+	}
+
+	val := reflect.ValueOf(objs)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice {
+		return errors.New("objects must be a slice")
+	}
+	total := val.Len()
+	if total == 0 {
+		return nil
+	}
+
+	// the schema is updated once, rather than for every batch
+	bc := c
+	if c.options.autoSchema {
+		schemaObj, err := checkObject(objs)
+		if err != nil {
+			return err
+		}
+		if err := c.updateSchema(ctx, schemaObj); err != nil {
+			return err
+		}
+		bc.options.autoSchema = false
+	}
+
+	var mu sync.Mutex
+	done := 0
+	batchErr := &BatchError{Failures: make(map[int]error), Total: total}
+
+	var g errgroup.Group
+	g.SetLimit(options.concurrency)
+	for start := 0; start < total; start += options.batchSize {
+		end := min(start+options.batchSize, total)
+		g.Go(func() error {
+			err := ctx.Err()
+			if err == nil {
+				err = bc.insert(ctx, nil, val.Slice(start, end).Interface())
+			}
+
+			// the objects failing validation are indexed within the batch
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				for i := range validationErr.Fields {
+					validationErr.Fields[i].Index += start
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				c.logger.Error(err, "Failed to insert batch", "start", start, "end", end)
+				batchErr.errs = append(batchErr.errs, err)
+				for i := start; i < end; i++ {
+					batchErr.Failures[i] = err
+				}
+			} else {
+				c.logger.V(2).Info("Inserted batch", "start", start, "end", end)
+			}
+			done += end - start
+			if options.progress != nil {
+				options.progress(done, total)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	if len(batchErr.errs) > 0 {
+		return batchErr
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientInsertBatch(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "InsertBatchWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "InsertBatchWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, client.Insert(ctx, &TestEntity{Name: "Existing", CreatedAt: time.Now()}))
+
+			const total = 95
+			entities := make([]*TestEntity, total)
+			for i := range entities {
+				entities[i] = &TestEntity{
+					Name:        fmt.Sprintf("Batch Entity %02d", i),
+					Description: "Inserted in a batch",
+					CreatedAt:   time.Now(),
+				}
+			}
+			// the batch holding index 23 violates the unique name constraint
+			entities[23].Name = "Existing"
+
+			var progress []int
+			err := client.InsertBatch(ctx, entities,
+				modusgraph.WithBatchSize(10),
+				modusgraph.WithConcurrency(3),
+				modusgraph.WithProgress(func(done, total int) {
+					progress = append(progress, done)
+				}))
+			require.Error(t, err, "InsertBatch should report the failed batch")
+
+			var batchErr *modusgraph.BatchError
+			require.ErrorAs(t, err, &batchErr)
+			require.Equal(t, total, batchErr.Total)
+			require.Len(t, batchErr.Failures, 10, "Every object of the failed batch should be reported")
+			for i := 20; i < 30; i++ {
+				require.Contains(t, batchErr.Failures, i)
+				require.Empty(t, entities[i].UID, "Objects of the failed batch should not be inserted")
+			}
+			if strings.HasPrefix(tc.uri, "file://") {
+				var uniqueErr *modusgraph.UniqueError
+				require.ErrorAs(t, err, &uniqueErr, "The cause of the failure should be reported")
+			}
+
+			require.Len(t, progress, 10, "Progress should be reported after each batch")
+			require.Equal(t, total, progress[len(progress)-1])
+			require.IsIncreasing(t, progress)
+
+			for i, entity := range entities {
+				if i < 20 || i >= 30 {
+					require.NotEmpty(t, entity.UID, "Objects of successful batches should be inserted")
+				}
+			}
+			inserted, err := modusgraph.Find[TestEntity](ctx, client,
+				modusgraph.WithFilter("eq(description, $desc)", map[string]string{"$desc": "Inserted in a batch"}),
+				modusgraph.WithFirst(1000))
+			require.NoError(t, err)
+			require.Len(t, inserted, total-10)
+
+			require.NoError(t, client.InsertBatch(ctx, []*TestEntity{}), "Empty slices should be accepted")
+			require.Error(t, client.InsertBatch(ctx, &TestEntity{Name: "Single"}), "Objects must be a slice")
+		})
+	}
+}
@@ -17,7 +17,10 @@ import (
 	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/hypermodeinc/dgraph/v25/x"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -45,75 +48,98 @@ func (s *serverWrapper) Query(ctx context.Context, req *api.Request) (*api.Respo
 	}
 	s.engine.logger.V(2).Info("Query using namespace", "namespaceID", ns.ID())
 
-	if len(req.Mutations) > 0 {
-		s.engine.logger.V(3).Info("Mutating", "mutations", req.Mutations)
+	if len(req.Mutations) > 0 && req.Query != "" {
+		s.engine.logger.V(3).Info("Upserting", "query", req.Query, "mutations", req.Mutations, "startTs", req.StartTs)
 
-		uids, err := ns.Mutate(ctx, req.Mutations)
+		resp, uids, err := s.engine.upsertTxn(ctx, ns, req.StartTs, req.Query, req.Vars, req.Mutations, req.CommitNow)
 		if err != nil {
-			return nil, fmt.Errorf("engine mutation error: %w", err)
+			return nil, grpcError(fmt.Errorf("engine upsert error: %w", err))
 		}
+		resp.Uids = uidMap(uids)
+		return resp, nil
+	}
 
-		uidMap := make(map[string]string)
-		for k, v := range uids {
-			if strings.HasPrefix(k, "_:") {
-				uidMap[k[2:]] = fmt.Sprintf("0x%x", v)
-			} else {
-				uidMap[k] = fmt.Sprintf("0x%x", v)
-			}
+	if len(req.Mutations) > 0 {
+		s.engine.logger.V(3).Info("Mutating", "mutations", req.Mutations, "startTs", req.StartTs)
+
+		uids, tc, err := s.engine.mutateTxn(ctx, ns, req.StartTs, req.Mutations, req.CommitNow)
+		if err != nil {
+			return nil, grpcError(fmt.Errorf("engine mutation error: %w", err))
 		}
 
 		return &api.Response{
-			Uids: uidMap,
+			Txn:  tc,
+			Uids: uidMap(uids),
 		}, nil
 	}
 
-	return ns.QueryWithVars(ctx, req.Query, req.Vars)
+	// Read-only requests are served from the snapshot of their read timestamp, which is
+	// the latest one for the first request of a transaction, while a read-write
+	// transaction reads at its start timestamp so it observes its own pending writes.
+	if req.ReadOnly {
+		return s.engine.queryAt(ctx, ns, req.StartTs, req.Query, req.Vars)
+	}
+
+	startTs := req.StartTs
+	if startTs == 0 {
+		if startTs, err = s.engine.startReadTxn(ctx); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := s.engine.queryTxn(ctx, ns, startTs, req.Query, req.Vars)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	resp.Txn = &api.TxnContext{StartTs: startTs}
+	return resp, nil
+}
+
+// uidMap converts the UIDs assigned to blank nodes to the format of a Dgraph response,
+// which names them without the _: prefix.
+func uidMap(uids map[string]uint64) map[string]string {
+	m := make(map[string]string, len(uids))
+	for k, v := range uids {
+		m[strings.TrimPrefix(k, "_:")] = fmt.Sprintf("0x%x", v)
+	}
+	return m
 }
 
 // CommitOrAbort implements the Dgraph CommitOrAbort method
 func (s *serverWrapper) CommitOrAbort(ctx context.Context, tc *api.TxnContext) (*api.TxnContext, error) {
-	var ns *Namespace
-
-	nsID, err := x.ExtractNamespace(ctx)
-	if err != nil || nsID == 0 {
-		ns = s.engine.GetDefaultNamespace()
-	} else {
-		ns, err = s.engine.GetNamespace(nsID)
-		if err != nil {
-			return nil, fmt.Errorf("error getting namespace %d: %w", nsID, err)
-		}
-	}
-	s.engine.logger.V(2).Info("CommitOrAbort called with transaction", "transaction", tc, "namespaceID", ns.ID())
+	s.engine.logger.V(2).Info("CommitOrAbort called with transaction", "transaction", tc)
 
 	if tc.Aborted {
+		if err := s.engine.abortTxn(ctx, tc.StartTs); err != nil {
+			return nil, fmt.Errorf("error aborting transaction: %w", err)
+		}
+		s.engine.logger.V(2).Info("Transaction aborted", "startTs", tc.StartTs)
 		return tc, nil
 	}
 
-	// For commit, we need to make a dummy mutation that has no effect but will trigger the commit
-	// This approach uses an empty mutation with CommitNow:true to leverage the Engine's existing
-	// transaction commit mechanism
-	emptyMutation := &api.Mutation{
-		CommitNow: true,
-	}
-
-	// We can't directly attach the transaction ID to the context in this way,
-	// but the Server implementation should handle the transaction context
-	// using the StartTs value in the empty mutation
-
-	// Send the mutation through the Engine
-	_, err = ns.Mutate(ctx, []*api.Mutation{emptyMutation})
+	commitTs, err := s.engine.commitTxn(ctx, tc.StartTs)
 	if err != nil {
-		return nil, fmt.Errorf("error committing transaction: %w", err)
+		return nil, grpcError(fmt.Errorf("error committing transaction: %w", err))
 	}
+	s.engine.logger.V(2).Info("Transaction committed successfully", "startTs", tc.StartTs, "commitTs", commitTs)
 
-	s.engine.logger.V(2).Info("Transaction committed successfully")
-
-	response := &api.TxnContext{
+	return &api.TxnContext{
 		StartTs:  tc.StartTs,
-		CommitTs: tc.StartTs + 1, // We don't know the actual commit timestamp, but this works for testing
-	}
+		CommitTs: commitTs,
+	}, nil
+}
 
-	return response, nil
+// grpcError converts an aborted transaction into the gRPC status returned by a
+// Dgraph Alpha, which dgo reports to callers as dgo.ErrAborted, and a violated unique
+// constraint into a status the client converts back into a UniqueError.
+func grpcError(err error) error {
+	if errors.Is(err, dgo.ErrAborted) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	var uniqueErr *UniqueError
+	if errors.As(err, &uniqueErr) {
+		return uniqueErrorStatus(uniqueErr)
+	}
+	return err
 }
 
 // Login implements the Dgraph Login method
@@ -151,21 +177,46 @@ func (s *serverWrapper) Alter(ctx context.Context, op *api.Operation) (*api.Payl
 			s.engine.logger.Error(err, "Error dropping all")
 			return nil, fmt.Errorf("error dropping all: %w", err)
 		}
+	case op.DropAttr != "" || op.DropOp == api.Operation_ATTR:
+		pred := op.DropAttr
+		if pred == "" {
+			pred = op.DropValue
+		}
+		if pred == "" {
+			return nil, errors.New("drop value must not be empty when dropping an attribute")
+		}
+		err = ns.DropPredicate(ctx, pred)
+		if err != nil {
+			s.engine.logger.Error(err, "Error dropping predicate", "predicate", pred)
+			return nil, fmt.Errorf("error dropping predicate: %w", err)
+		}
 	case op.DropOp != 0:
 		switch op.DropOp {
+		case api.Operation_ALL:
+			err = ns.DropAll(ctx)
+			if err != nil {
+				s.engine.logger.Error(err, "Error dropping all")
+				return nil, fmt.Errorf("error dropping all: %w", err)
+			}
 		case api.Operation_DATA:
 			err = ns.DropData(ctx)
 			if err != nil {
 				s.engine.logger.Error(err, "Error dropping data")
 				return nil, fmt.Errorf("error dropping data: %w", err)
 			}
+		case api.Operation_TYPE:
+			if op.DropValue == "" {
+				return nil, errors.New("drop value must not be empty when dropping a type")
+			}
+			err = ns.DropType(ctx, op.DropValue)
+			if err != nil {
+				s.engine.logger.Error(err, "Error dropping type", "type", op.DropValue)
+				return nil, fmt.Errorf("error dropping type: %w", err)
+			}
 		default:
 			s.engine.logger.Error(nil, "Unsupported drop operation")
 			return nil, fmt.Errorf("unsupported drop operation: %d", op.DropOp)
 		}
-	case op.DropAttr != "":
-		s.engine.logger.Error(nil, "Drop attribute not implemented yet")
-		return nil, errors.New("drop attribute not implemented yet")
 
 	default:
 		return nil, errors.New("unsupported alter operation")
@@ -211,19 +262,55 @@ func bufDialer(listener *bufconn.Listener) func(context.Context, string) (net.Co
 	}
 }
 
-// createDgraphClient creates a Dgraph client that connects to the bufconn server
-func createDgraphClient(ctx context.Context, listener *bufconn.Listener) (*dgo.Dgraph, error) {
+// createDgraphClient creates a Dgraph client that connects to the bufconn server. The
+// Dgraph client doesn't close the connection, which is returned to be closed with it.
+func createDgraphClient(ctx context.Context, listener *bufconn.Listener,
+	opts ...grpc.DialOption) (*dgo.Dgraph, *grpc.ClientConn, error) {
 	// Create a gRPC connection using the bufconn dialer
-	// nolint:staticcheck // SA1019: grpc.DialContext is deprecated
-	conn, err := grpc.DialContext(ctx, "bufnet",
+	opts = append(opts,
 		grpc.WithContextDialer(bufDialer(listener)),
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// nolint:staticcheck // SA1019: grpc.DialContext is deprecated
+	conn, err := grpc.DialContext(ctx, "bufnet", opts...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Create a Dgraph client
 	dgraphClient := api.NewDgraphClient(conn)
 	// nolint:staticcheck // SA1019: dgo.NewDgraphClient is deprecated but works with our current setup
-	return dgo.NewDgraphClient(dgraphClient), nil
+	return dgo.NewDgraphClient(dgraphClient), conn, nil
+}
+
+// namespaceInterceptor attaches the namespace ID to every outgoing request, which
+// serverWrapper uses to route the request to that namespace.
+func namespaceInterceptor(nsID uint64) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any,
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(x.AttachNamespaceOutgoing(ctx, nsID), method, req, reply, cc, opts...)
+	}
+}
+
+// softDeleteKey is the metadata key holding the predicate that marks the nodes deleted
+// by the client of a request, which don't hold the values of their @unique predicates.
+const softDeleteKey = "modusgraph-soft-delete"
+
+// softDeleteInterceptor attaches the soft delete predicate to every outgoing request of
+// a client created with WithSoftDelete.
+func softDeleteInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any,
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, softDeleteKey, SoftDeletePredicate)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// softDeletePredicate returns the predicate attached by softDeleteInterceptor to the
+// incoming request of ctx, or "" if its client doesn't soft delete nodes.
+func softDeletePredicate(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(softDeleteKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
 }
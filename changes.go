@@ -0,0 +1,629 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/go-logr/logr"
+	"github.com/hypermodeinc/dgraph/v25/posting"
+	"github.com/hypermodeinc/dgraph/v25/protos/pb"
+	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
+	"github.com/hypermodeinc/dgraph/v25/worker"
+	"github.com/hypermodeinc/dgraph/v25/x"
+)
+
+const (
+	// changeLogPrefix is the prefix of the keys of the change log. Its first byte is not
+	// used by Dgraph keys, so the log is neither read as posting lists nor dropped with
+	// the data of a namespace.
+	changeLogPrefix = "\x80modusgraph.changes"
+
+	// changeLogTrimInterval is how often entries older than the retention are removed
+	changeLogTrimInterval = time.Minute
+
+	// maxQueuedChanges is the number of changes a subscriber can fall behind by before it
+	// is dropped
+	maxQueuedChanges = 100000
+)
+
+var (
+	ErrChangesUnavailable = errors.New("changes since the requested commit are no longer available")
+	ErrRemoteChangeFeed   = errors.New("change feeds are only available for embedded databases")
+	ErrSubscriberOverflow = errors.New("subscriber fell too far behind the committed changes")
+)
+
+// ChangeOp is the kind of change made to a predicate of a node.
+type ChangeOp int
+
+const (
+	// ChangeSet adds a value or an edge to the predicate.
+	ChangeSet ChangeOp = iota
+	// ChangeDelete removes a value or an edge from the predicate.
+	ChangeDelete
+)
+
+func (op ChangeOp) String() string {
+	if op == ChangeDelete {
+		return "delete"
+	}
+	return "set"
+}
+
+// Change is a committed change to a predicate of a node.
+type Change struct {
+	Namespace uint64
+	CommitTs  uint64
+	UID       uint64
+	Predicate string
+	Lang      string
+	Op        ChangeOp
+	// Value is the value set or deleted, or the UID of the target node for an edge.
+	// It is nil if all the values of the predicate were deleted.
+	Value any
+	// OldValue is the value of a single valued predicate before the change, nil if it
+	// had none. It is always nil for list predicates.
+	OldValue any
+}
+
+// ChangeFilter selects the changes delivered by Subscribe.
+type ChangeFilter struct {
+	// Namespaces restricts the changes to those namespaces, all of them if empty.
+	Namespaces []uint64
+	// Predicates restricts the changes to those predicates, all of them if empty.
+	Predicates []string
+	// SinceTs makes the subscription start with the changes committed after SinceTs,
+	// read from the change log kept for the retention set with WithChangeRetention.
+	// If zero, only the changes committed after subscribing are delivered.
+	SinceTs uint64
+}
+
+func (f ChangeFilter) matches(r changeRecord) bool {
+	return (len(f.Namespaces) == 0 || slices.Contains(f.Namespaces, r.Namespace)) &&
+		(len(f.Predicates) == 0 || slices.Contains(f.Predicates, r.Predicate))
+}
+
+// changeRecord is a change of an open transaction or of the change log, with its values
+// in the encoding they are stored with.
+type changeRecord struct {
+	Namespace uint64         `json:"ns"`
+	UID       uint64         `json:"uid"`
+	Predicate string         `json:"pred"`
+	Lang      string         `json:"lang,omitempty"`
+	Op        ChangeOp       `json:"op"`
+	Value     *recordedValue `json:"value,omitempty"`
+	OldValue  *recordedValue `json:"old,omitempty"`
+
+	// skip is set for a deletion of all values of a predicate that has none
+	skip bool
+	// scalar is the edge to take Value from once it has been converted
+	scalar *pb.DirectedEdge
+}
+
+func (r changeRecord) change(commitTs uint64) Change {
+	return Change{
+		Namespace: r.Namespace,
+		CommitTs:  commitTs,
+		UID:       r.UID,
+		Predicate: r.Predicate,
+		Lang:      r.Lang,
+		Op:        r.Op,
+		Value:     r.Value.value(),
+		OldValue:  r.OldValue.value(),
+	}
+}
+
+// recordedValue is a value in its binary encoding, or the UID of a node.
+type recordedValue struct {
+	Type types.TypeID `json:"type"`
+	Data []byte       `json:"data,omitempty"`
+	UID  uint64       `json:"uid,omitempty"`
+}
+
+func (v *recordedValue) value() any {
+	if v == nil {
+		return nil
+	}
+	if v.Type == types.UidID {
+		return v.UID
+	}
+	val, err := types.Convert(types.Val{Tid: v.Type, Value: v.Data}, v.Type)
+	if err != nil {
+		return string(v.Data)
+	}
+	return val.Value
+}
+
+// changeLogEntry is the change log entry of a commit
+type changeLogEntry struct {
+	Time    time.Time      `json:"time"`
+	Changes []changeRecord `json:"changes"`
+}
+
+// changeFeed collects the changes of open transactions and delivers them to the
+// subscribers once they are committed, keeping a log of them for the retention period.
+//
+// The change feed is not safe for concurrent use, callers must hold the engine write lock.
+type changeFeed struct {
+	retention time.Duration
+	logger    logr.Logger
+
+	// pending maps the start timestamp of an open transaction to its changes
+	pending map[uint64][]changeRecord
+	// uncaptured holds the start timestamps of the open transactions that wrote while
+	// nothing could consume their changes, whose changes are not captured
+	uncaptured  map[uint64]struct{}
+	subscribers map[*subscriber]struct{}
+	// logStart is the commit timestamp after which every commit is in the change log
+	logStart uint64
+	lastTrim time.Time
+}
+
+func newChangeFeed(retention time.Duration, logger logr.Logger) *changeFeed {
+	return &changeFeed{
+		retention:   retention,
+		logger:      logger,
+		pending:     make(map[uint64][]changeRecord),
+		uncaptured:  make(map[uint64]struct{}),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// open loads the start of the change log, which begins at readTs if changes were not
+// logged so far. Without retention, any previous log is abandoned.
+func (f *changeFeed) open(readTs uint64) error {
+	txn := worker.State.Pstore.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+
+	f.logStart = readTs
+	if f.retention == 0 {
+		return f.setLogStart(readTs, readTs, nil)
+	}
+	item, err := txn.Get(changeLogKey(0))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return f.setLogStart(readTs, readTs, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading change log: %w", err)
+	}
+	return item.Value(func(val []byte) error {
+		f.logStart = binary.BigEndian.Uint64(val)
+		return nil
+	})
+}
+
+// reset discards the changes of open transactions and restarts the change log at readTs,
+// e.g. after the data was dropped.
+func (f *changeFeed) reset(readTs uint64) {
+	clear(f.pending)
+	clear(f.uncaptured)
+	if err := f.setLogStart(readTs, readTs, nil); err != nil {
+		f.logger.Error(err, "Failed to reset change log")
+	}
+}
+
+// setLogStart records that the change log is complete after logStart, in a write at ts.
+// The entries of deleted commits are removed with it. Without retention, the log is removed.
+func (f *changeFeed) setLogStart(ts, logStart uint64, deleted [][]byte) error {
+	f.logStart = logStart
+	txn := worker.State.Pstore.NewTransactionAt(ts, true)
+	defer txn.Discard()
+
+	for _, key := range deleted {
+		if err := txn.Delete(key); err != nil {
+			return err
+		}
+	}
+	var err error
+	if f.retention == 0 {
+		err = txn.Delete(changeLogKey(0))
+	} else {
+		err = txn.Set(changeLogKey(0), binary.BigEndian.AppendUint64(nil, logStart))
+	}
+	if err != nil {
+		return err
+	}
+	return txn.CommitAt(ts, nil)
+}
+
+// capturing reports whether the changes of the transaction started at startTs are to be
+// recorded, which is the case if they can be logged or delivered to a subscriber. Once a
+// write of the transaction was not captured, the others are not either, so that commits
+// are never delivered in part.
+func (f *changeFeed) capturing(startTs uint64) bool {
+	if _, ok := f.uncaptured[startTs]; ok {
+		return false
+	}
+	if f.retention == 0 && len(f.subscribers) == 0 {
+		f.uncaptured[startTs] = struct{}{}
+		return false
+	}
+	return true
+}
+
+// record adds changes to the transaction started at startTs.
+func (f *changeFeed) record(startTs uint64, changes []changeRecord) {
+	if len(changes) > 0 {
+		f.pending[startTs] = append(f.pending[startTs], changes...)
+	}
+}
+
+// discard drops the changes of the transaction started at startTs.
+func (f *changeFeed) discard(startTs uint64) {
+	delete(f.pending, startTs)
+	delete(f.uncaptured, startTs)
+}
+
+// commit logs the changes of the transaction started at startTs as committed at commitTs
+// and delivers them to the subscribers.
+func (f *changeFeed) commit(startTs, commitTs uint64) {
+	changes := f.pending[startTs]
+	delete(f.pending, startTs)
+	if _, ok := f.uncaptured[startTs]; ok {
+		// resuming from before this commit would miss it
+		delete(f.uncaptured, startTs)
+		f.logStart = commitTs
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	if f.retention == 0 {
+		f.logStart = commitTs
+	} else if err := f.log(commitTs, changes); err != nil {
+		// resuming from before this commit would miss it
+		f.logger.Error(err, "Failed to log changes", "commitTs", commitTs)
+		f.logStart = commitTs
+	}
+
+	for s := range f.subscribers {
+		if !s.push(commitTs, changes) {
+			f.logger.Error(ErrSubscriberOverflow, "Dropping subscriber", "commitTs", commitTs)
+			f.unsubscribe(s)
+		}
+	}
+}
+
+// log writes the change log entry of the commit at commitTs, and removes the entries
+// older than the retention period from time to time.
+func (f *changeFeed) log(commitTs uint64, changes []changeRecord) error {
+	now := time.Now()
+	data, err := json.Marshal(changeLogEntry{Time: now, Changes: changes})
+	if err != nil {
+		return err
+	}
+	txn := worker.State.Pstore.NewTransactionAt(commitTs, true)
+	defer txn.Discard()
+	if err := txn.Set(changeLogKey(commitTs), data); err != nil {
+		return err
+	}
+	if err := txn.CommitAt(commitTs, nil); err != nil {
+		return err
+	}
+
+	if now.Sub(f.lastTrim) < changeLogTrimInterval {
+		return nil
+	}
+	f.lastTrim = now
+	return f.trim(commitTs, now.Add(-f.retention))
+}
+
+// trim removes the change log entries written before cutoff, in a write at ts.
+func (f *changeFeed) trim(ts uint64, cutoff time.Time) error {
+	logStart := f.logStart
+	var deleted [][]byte
+	err := f.iterate(f.logStart, func(commitTs uint64, entry changeLogEntry) bool {
+		if !entry.Time.Before(cutoff) {
+			return false
+		}
+		deleted = append(deleted, changeLogKey(commitTs))
+		logStart = commitTs
+		return true
+	})
+	if err != nil || len(deleted) == 0 {
+		return err
+	}
+	f.logger.V(1).Info("Trimming change log", "entryCount", len(deleted), "logStart", logStart)
+	return f.setLogStart(ts, logStart, deleted)
+}
+
+// iterate calls fn with the change log entries of the commits after sinceTs, in commit
+// order, until it returns false.
+func (f *changeFeed) iterate(sinceTs uint64, fn func(uint64, changeLogEntry) bool) error {
+	txn := worker.State.Pstore.NewTransactionAt(math.MaxUint64, false)
+	defer txn.Discard()
+
+	iopts := badger.DefaultIteratorOptions
+	iopts.Prefix = []byte(changeLogPrefix)
+	it := txn.NewIterator(iopts)
+	defer it.Close()
+
+	for it.Seek(changeLogKey(sinceTs + 1)); it.Valid(); it.Next() {
+		key := it.Item().Key()
+		commitTs := binary.BigEndian.Uint64(key[len(key)-8:])
+		var entry changeLogEntry
+		if err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, &entry)
+		}); err != nil {
+			return fmt.Errorf("error reading change log entry %d: %w", commitTs, err)
+		}
+		if !fn(commitTs, entry) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// changeLogKey returns the key of the change log entry of the commit at commitTs. The
+// key with timestamp zero holds the commit timestamp after which the log is complete.
+func changeLogKey(commitTs uint64) []byte {
+	return binary.BigEndian.AppendUint64([]byte(changeLogPrefix), commitTs)
+}
+
+// subscribe registers a subscriber for the changes matching filter, starting with the
+// logged changes committed after filter.SinceTs.
+func (f *changeFeed) subscribe(filter ChangeFilter) (*subscriber, error) {
+	s := &subscriber{
+		filter: filter,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		out:    make(chan Change),
+	}
+	if filter.SinceTs > 0 {
+		if filter.SinceTs < f.logStart {
+			return nil, ErrChangesUnavailable
+		}
+		overflow := false
+		err := f.iterate(filter.SinceTs, func(commitTs uint64, entry changeLogEntry) bool {
+			overflow = !s.push(commitTs, entry.Changes)
+			return !overflow
+		})
+		if err != nil {
+			return nil, err
+		}
+		if overflow {
+			return nil, ErrSubscriberOverflow
+		}
+	}
+	f.subscribers[s] = struct{}{}
+	return s, nil
+}
+
+// unsubscribe stops delivering changes to s.
+func (f *changeFeed) unsubscribe(s *subscriber) {
+	if _, ok := f.subscribers[s]; ok {
+		delete(f.subscribers, s)
+		close(s.done)
+	}
+}
+
+// close stops delivering changes to all subscribers.
+func (f *changeFeed) close() {
+	for s := range f.subscribers {
+		f.unsubscribe(s)
+	}
+}
+
+// subscriber queues the changes delivered to a subscription, so that a slow reader
+// never holds up commits. A reader that falls more than maxQueuedChanges behind is
+// dropped instead.
+type subscriber struct {
+	filter ChangeFilter
+
+	mu    sync.Mutex
+	queue []Change
+	// wake signals that changes were queued
+	wake chan struct{}
+	// done is closed when the subscription is removed from the feed
+	done chan struct{}
+	out  chan Change
+}
+
+// push queues the changes of the commit at commitTs that match the filter. It returns
+// false, queuing none of them, if the queue would exceed maxQueuedChanges.
+func (s *subscriber) push(commitTs uint64, changes []changeRecord) bool {
+	s.mu.Lock()
+	queued := len(s.queue)
+	for _, r := range changes {
+		if !s.filter.matches(r) {
+			continue
+		}
+		if len(s.queue) == maxQueuedChanges {
+			s.queue = s.queue[:queued]
+			s.mu.Unlock()
+			return false
+		}
+		s.queue = append(s.queue, r.change(commitTs))
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// run sends the queued changes to the out channel until ctx is done or the subscription
+// is removed, and closes it.
+func (s *subscriber) run(ctx context.Context) {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, change := range queue {
+			select {
+			case s.out <- change:
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+
+		select {
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Subscribe returns a channel delivering the changes committed to the engine that match
+// the filter, in commit order. Changes made by dropping data or schema are not included.
+// The channel is closed when ctx is done or the engine is closed, or when the reader falls
+// more than 100000 changes behind. To resume after a restart, pass the CommitTs of the last
+// change processed as filter.SinceTs. ErrSubscriberOverflow is returned if more changes
+// than that were committed since then.
+func (engine *Engine) Subscribe(ctx context.Context, filter ChangeFilter) (<-chan Change, error) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return nil, ErrClosedEngine
+	}
+	s, err := engine.feed.subscribe(filter)
+	if err != nil {
+		return nil, err
+	}
+	engine.logger.V(1).Info("Subscribed to changes", "sinceTs", filter.SinceTs)
+
+	go func() {
+		s.run(ctx)
+		engine.mutex.Lock()
+		defer engine.mutex.Unlock()
+		engine.feed.unsubscribe(s)
+	}()
+	return s.out, nil
+}
+
+// Subscribe returns a channel delivering the changes committed to the namespace that
+// match the filter. See Engine.Subscribe.
+func (ns *Namespace) Subscribe(ctx context.Context, filter ChangeFilter) (<-chan Change, error) {
+	filter.Namespaces = []uint64{ns.ID()}
+	return ns.engine.Subscribe(ctx, filter)
+}
+
+// Subscribe implements receiving the changes committed to the namespace of the client.
+func (c client) Subscribe(ctx context.Context, filter ChangeFilter) (<-chan Change, error) {
+	var changes <-chan Change
+	err := c.intercept(ctx, "Subscribe", filter, func(ctx context.Context) error {
+		if !c.isLocal() {
+			return ErrRemoteChangeFeed
+		}
+		var err error
+		changes, err = c.ns.Subscribe(ctx, filter)
+		return err
+	})
+	return changes, err
+}
+
+// pendingChanges returns the changes the edges make in the transaction started at startTs,
+// with the previous values of single valued predicates. It must be called before the edges
+// are applied, and setChangeValues afterwards, once the values have been converted to the
+// types of the schema.
+func pendingChanges(startTs uint64, edges []*pb.DirectedEdge) ([]changeRecord, error) {
+	txn := posting.Oracle().RegisterStartTs(startTs)
+	changes := make([]changeRecord, len(edges))
+	for i, edge := range edges {
+		ns, attr := x.ParseNamespaceAttr(edge.Attr)
+		changes[i] = changeRecord{Namespace: ns, UID: edge.Entity, Predicate: attr, Lang: edge.Lang}
+		if edge.Op == pb.DirectedEdge_DEL {
+			changes[i].Op = ChangeDelete
+		}
+
+		pl, err := txn.Get(x.DataKey(edge.Attr, edge.Entity))
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case isDeleteAll(edge):
+			// deleting all predicates of a node covers every predicate of its types
+			empty, err := pl.IsEmpty(startTs, 0)
+			if err != nil {
+				return nil, err
+			}
+			changes[i].skip = empty
+		case edge.ValueId != 0:
+			changes[i].Value = &recordedValue{Type: types.UidID, UID: edge.ValueId}
+		default:
+			changes[i].scalar = edge
+		}
+		if !changes[i].skip && !schema.State().IsList(edge.Attr) {
+			if changes[i].OldValue, err = currentValue(pl, startTs, edge); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// setChangeValues fills in the values of the changes returned by pendingChanges, and
+// returns those that changed anything.
+func setChangeValues(changes []changeRecord) []changeRecord {
+	result := changes[:0]
+	for _, r := range changes {
+		if r.skip {
+			continue
+		}
+		if edge := r.scalar; edge != nil {
+			r.Value = &recordedValue{Type: types.TypeID(edge.ValueType), Data: edge.Value}
+			r.scalar = nil
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// currentValue returns the value of the single valued predicate of pl for the language of
+// edge, as seen by the transaction started at startTs.
+func currentValue(pl *posting.List, startTs uint64, edge *pb.DirectedEdge) (*recordedValue, error) {
+	if typ, err := schema.State().TypeOf(edge.Attr); err == nil && typ == types.UidID {
+		uids, err := pl.Uids(posting.ListOptions{ReadTs: startTs})
+		if err != nil || len(uids.Uids) == 0 {
+			return nil, err
+		}
+		return &recordedValue{Type: types.UidID, UID: uids.Uids[0]}, nil
+	}
+
+	var val types.Val
+	var err error
+	if edge.Lang != "" {
+		val, err = pl.ValueForTag(startTs, edge.Lang)
+	} else {
+		val, err = pl.Value(startTs)
+	}
+	if errors.Is(err, posting.ErrNoValue) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, _ := val.Value.([]byte)
+	return &recordedValue{Type: val.Tid, Data: data}, nil
+}
+
+// isDeleteAll reports whether edge deletes all values of its predicate.
+func isDeleteAll(edge *pb.DirectedEdge) bool {
+	return edge.Op == pb.DirectedEdge_DEL && edge.ValueId == 0 &&
+		types.TypeID(edge.ValueType) == types.DefaultID && string(edge.Value) == x.Star
+}
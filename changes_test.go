@@ -0,0 +1,191 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+type ChangeItem struct {
+	Name  string `json:"name,omitempty" dgraph:"index=exact"`
+	Count int    `json:"count,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+// nextChange returns the next change delivered on changes, failing after a timeout.
+func nextChange(t *testing.T, changes <-chan modusgraph.Change) modusgraph.Change {
+	t.Helper()
+	select {
+	case change, ok := <-changes:
+		require.True(t, ok, "The change feed should be open")
+		return change
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for a change")
+		return modusgraph.Change{}
+	}
+}
+
+// nextChanges returns the next n changes delivered on changes, by predicate.
+func nextChanges(t *testing.T, changes <-chan modusgraph.Change, n int) map[string]modusgraph.Change {
+	t.Helper()
+	result := make(map[string]modusgraph.Change, n)
+	for range n {
+		change := nextChange(t, changes)
+		result[change.Predicate] = change
+	}
+	return result
+}
+
+func TestClientSubscribe(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "SubscribeWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "SubscribeWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			filter := modusgraph.ChangeFilter{Predicates: []string{"name", "count"}}
+			changes, err := client.Subscribe(ctx, filter)
+			if strings.HasPrefix(tc.uri, "dgraph://") {
+				require.ErrorIs(t, err, modusgraph.ErrRemoteChangeFeed)
+				return
+			}
+			require.NoError(t, err)
+
+			item := ChangeItem{Name: "first", Count: 1}
+			require.NoError(t, client.Insert(ctx, &item))
+			uid, err := strconv.ParseUint(item.UID, 0, 64)
+			require.NoError(t, err)
+
+			inserted := nextChanges(t, changes, 2)
+			require.Equal(t, modusgraph.ChangeSet, inserted["name"].Op)
+			require.Equal(t, uid, inserted["name"].UID)
+			require.Equal(t, "first", inserted["name"].Value)
+			require.Nil(t, inserted["name"].OldValue)
+			require.EqualValues(t, 1, inserted["count"].Value)
+			require.NotZero(t, inserted["name"].CommitTs)
+			require.Equal(t, inserted["name"].CommitTs, inserted["count"].CommitTs,
+				"Changes of a commit should have its commit timestamp")
+
+			// changes are only delivered once committed
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err)
+			require.NoError(t, tx.UpdateFields(ctx, &ChangeItem{UID: item.UID, Name: "discarded"}, "name"))
+			require.NoError(t, tx.Discard(ctx))
+
+			item.Name = "second"
+			require.NoError(t, client.UpdateFields(ctx, &item, "name"))
+			updated := nextChange(t, changes)
+			require.Equal(t, "name", updated.Predicate)
+			require.Equal(t, "second", updated.Value, "Discarded changes should not be delivered")
+			require.Equal(t, "first", updated.OldValue)
+			require.Greater(t, updated.CommitTs, inserted["name"].CommitTs)
+
+			require.NoError(t, client.Delete(ctx, []string{item.UID}))
+			deleted := nextChanges(t, changes, 2)
+			require.Equal(t, modusgraph.ChangeDelete, deleted["name"].Op)
+			require.Nil(t, deleted["name"].Value, "Deleting all values should have no value")
+			require.Equal(t, "second", deleted["name"].OldValue)
+			require.EqualValues(t, 1, deleted["count"].OldValue)
+
+			cancel()
+			_, ok := <-changes
+			require.False(t, ok, "The change feed should be closed with its context")
+		})
+	}
+}
+
+func TestEngineSubscribeResume(t *testing.T) {
+	dataDir := t.TempDir()
+	ctx := context.Background()
+	conf := modusgraph.NewDefaultConfig(dataDir).WithChangeRetention(time.Hour)
+
+	engine, err := modusgraph.NewEngine(conf)
+	require.NoError(t, err)
+	defer func() { engine.Close() }()
+	require.NoError(t, engine.GetDefaultNamespace().AlterSchema(ctx, "name: string ."))
+
+	setName := func(subject, name string) map[string]uint64 {
+		uids, err := engine.GetDefaultNamespace().Mutate(ctx, []*api.Mutation{{
+			Set: []*api.NQuad{{
+				Subject:     subject,
+				Predicate:   "name",
+				ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: name}},
+			}},
+		}})
+		require.NoError(t, err)
+		return uids
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	changes, err := engine.GetDefaultNamespace().Subscribe(subCtx, modusgraph.ChangeFilter{})
+	require.NoError(t, err)
+
+	subject := fmt.Sprintf("%#x", setName("_:item", "before restart")["_:item"])
+	processed := nextChange(t, changes)
+	setName(subject, "missed")
+	engine.Close()
+	_, ok := <-changes
+	require.False(t, ok, "The change feed should be closed with the engine")
+
+	engine, err = modusgraph.NewEngine(conf)
+	require.NoError(t, err)
+	changes, err = engine.Subscribe(subCtx, modusgraph.ChangeFilter{SinceTs: processed.CommitTs})
+	require.NoError(t, err)
+	missed := nextChange(t, changes)
+	require.Equal(t, "missed", missed.Value, "Changes after SinceTs should be delivered from the log")
+	require.Equal(t, "before restart", missed.OldValue)
+	require.Zero(t, missed.Namespace)
+
+	setName(subject, "live")
+	require.Equal(t, "live", nextChange(t, changes).Value)
+
+	_, err = engine.Subscribe(subCtx, modusgraph.ChangeFilter{SinceTs: 1})
+	require.ErrorIs(t, err, modusgraph.ErrChangesUnavailable,
+		"Changes from before the log started should be unavailable")
+
+	require.NoError(t, engine.GetDefaultNamespace().DropData(ctx))
+	logged, err := engine.Subscribe(subCtx, modusgraph.ChangeFilter{SinceTs: processed.CommitTs})
+	require.NoError(t, err, "Dropping the data should keep the change log")
+	require.Equal(t, "missed", nextChange(t, logged).Value)
+
+	require.NoError(t, engine.DropAll(ctx))
+}
@@ -255,8 +255,12 @@ func TestLocalClientSingleton(t *testing.T) {
 	require.Equal(t, client, client2, "Expected the same client instance")
 	defer client2.Close()
 
-	_, err = mg.NewClient("file://"+path, mg.WithAutoSchema(true)) // will create a new client "key"
-	require.Error(t, err, "Expected an error when creating a new client with different options")
+	client3, err := mg.NewClient("file://"+path, mg.WithAutoSchema(true)) // will create a new client "key"
+	require.NoError(t, err, "Expected clients with different options to share the engine of the path")
+	defer client3.Close()
+
+	_, err = mg.NewClient("file://" + GetTempDir(t))
+	require.Error(t, err, "Expected an error when creating a new client for another path")
 	require.ErrorIs(t, err, mg.ErrSingletonOnly)
 }
 
@@ -303,3 +307,83 @@ func TestRemoteClientAccess(t *testing.T) {
 	}()
 	wg.Wait()
 }
+
+func TestLocalClientNamespace(t *testing.T) {
+	path := GetTempDir(t)
+	ctx := context.Background()
+
+	_, err := mg.NewClient("file://"+path, mg.WithNamespace("tenant"))
+	require.Error(t, err, "Expected an error for a non-numeric namespace")
+
+	tenant, err := mg.NewClient("file://"+path, mg.WithNamespace("1"), mg.WithAutoSchema(true))
+	require.NoError(t, err)
+	defer func() {
+		tenant.Close()
+		mg.Shutdown()
+	}()
+	entity := TestEntity{Name: "Tenant Entity", CreatedAt: time.Now()}
+	require.NoError(t, tenant.Insert(ctx, &entity))
+
+	// the default namespace shares the tenant's engine but doesn't see its data, and
+	// dropping its data leaves the tenant intact
+	galaxy, err := mg.NewClient("file://"+path, mg.WithAutoSchema(true))
+	require.NoError(t, err, "Clients for other namespaces should share the engine of the path")
+	var entities []TestEntity
+	require.NoError(t, galaxy.UpdateSchema(ctx, TestEntity{}))
+	require.NoError(t, galaxy.Query(ctx, TestEntity{}).Nodes(&entities))
+	require.Empty(t, entities, "Tenant data should not be visible in the default namespace")
+	require.NoError(t, galaxy.Insert(ctx, &TestEntity{Name: "Galaxy Entity", CreatedAt: time.Now()}))
+	require.NoError(t, galaxy.DropData(ctx))
+	galaxy.Close()
+
+	otherTenant, err := mg.NewClient("file://"+path, mg.WithNamespace("1"), mg.WithPoolSize(5))
+	require.NoError(t, err)
+	defer otherTenant.Close()
+	for _, client := range []mg.Client{tenant, otherTenant} {
+		require.NoError(t, client.Query(ctx, TestEntity{}).Nodes(&entities),
+			"Closing a client should leave the engine open for the other namespaces")
+		require.Len(t, entities, 1, "Tenant data should survive dropping the default namespace's data")
+		require.Equal(t, "Tenant Entity", entities[0].Name)
+	}
+
+	resp, err := otherTenant.QueryRaw(ctx, `{ q(func: eq(name, "Tenant Entity")) { name } }`, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"q":[{"name":"Tenant Entity"}]}`, string(resp))
+}
+
+func TestLocalClientNamespaceDropAll(t *testing.T) {
+	path := GetTempDir(t)
+	ctx := context.Background()
+
+	_, err := mg.NewClient("file://"+path, mg.WithNamespace("2"))
+	require.ErrorIs(t, err, mg.ErrNonExistentDB, "Only the next namespace ID should be created")
+
+	first, err := mg.NewClient("file://"+path, mg.WithNamespace("1"), mg.WithAutoSchema(true))
+	require.NoError(t, err)
+	defer func() {
+		first.Close()
+		mg.Shutdown()
+	}()
+	require.NoError(t, first.Insert(ctx, &TestEntity{Name: "First Tenant Entity", CreatedAt: time.Now()}))
+
+	second, err := mg.NewClient("file://"+path, mg.WithNamespace("2"), mg.WithAutoSchema(true))
+	require.NoError(t, err)
+	require.NoError(t, second.Insert(ctx, &TestEntity{Name: "Second Tenant Entity", CreatedAt: time.Now()}))
+	require.NoError(t, second.DropAll(ctx))
+	schema, err := second.GetSchema(ctx)
+	require.NoError(t, err)
+	require.NotContains(t, schema, "TestEntity", "The tenant's schema should be dropped")
+	resp, err := second.QueryRaw(ctx, `{ q(func: has(name)) { name } }`, nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"q":[]}`, string(resp), "The tenant's data should be dropped")
+	second.Close()
+
+	var entities []TestEntity
+	require.NoError(t, first.Query(ctx, TestEntity{}).Nodes(&entities))
+	require.Len(t, entities, 1, "Other tenants should survive a tenant dropping all")
+	require.Equal(t, "First Tenant Entity", entities[0].Name)
+
+	first.Close()
+	_, err = first.QueryRaw(ctx, `{ q(func: has(name)) { name } }`, nil)
+	require.Error(t, err, "Closing the last client should close the engine")
+}
@@ -6,6 +6,8 @@
 package modusgraph
 
 import (
+	"time"
+
 	"github.com/go-logr/logr"
 )
 
@@ -13,6 +15,7 @@ type Config struct {
 	dataDir            string
 	cacheSizeMB        int
 	limitNormalizeNode int
+	changeRetention    time.Duration
 
 	// logger is used for structured logging
 	logger logr.Logger
@@ -45,6 +48,13 @@ func (cc Config) WithCacheSizeMB(size int) Config {
 	return cc
 }
 
+// WithChangeRetention keeps the committed changes in the data directory for the given
+// duration, so that subscribers can resume from an earlier commit with ChangeFilter.SinceTs
+func (cc Config) WithChangeRetention(d time.Duration) Config {
+	cc.changeRetention = d
+	return cc
+}
+
 func (cc Config) validate() error {
 	if cc.dataDir == "" {
 		return ErrEmptyDataDir
@@ -54,5 +64,9 @@ func (cc Config) validate() error {
 		return ErrInvalidCacheSize
 	}
 
+	if cc.changeRetention < 0 {
+		return ErrInvalidChangeRetention
+	}
+
 	return nil
 }
@@ -0,0 +1,355 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// cascadeTag is the dgraph tag option marking an edge whose target nodes are owned by
+// the node, and deleted with it when Delete is called with WithCascade
+const cascadeTag = "cascade"
+
+// deleteOptions holds the options of Delete.
+//
+// inbound: remove the edges of other nodes pointing to the deleted nodes.
+// cascade: the predicates of the owned edges to follow, by node type.
+// purge: also remove the soft delete marker, which is not part of any node type.
+// model: the type of the deleted objects, to call their BeforeDelete hook.
+type deleteOptions struct {
+	inbound bool
+	cascade map[string][]string
+	purge   bool
+	model   any
+}
+
+// DeleteOpt is a function that configures Delete.
+type DeleteOpt func(*deleteOptions)
+
+// WithInboundEdges makes Delete remove the edges of other nodes that point to the deleted
+// nodes, so they are not left dangling. Edges of predicates with a @reverse index are found
+// through it, other uid predicates are scanned on the nodes of the types declaring them.
+func WithInboundEdges() DeleteOpt {
+	return func(o *deleteOptions) {
+		o.inbound = true
+	}
+}
+
+// WithCascade makes Delete also delete the nodes owned by the deleted nodes, following the
+// edges tagged `dgraph:"cascade"` in the given models and in the types they lead to. A node
+// is matched to a model by its dgraph.type.
+func WithCascade(models ...any) DeleteOpt {
+	return func(o *deleteOptions) {
+		if o.cascade == nil {
+			o.cascade = make(map[string][]string)
+		}
+		for _, model := range models {
+			addCascadeEdges(o.cascade, reflect.TypeOf(model))
+		}
+	}
+}
+
+// WithModel gives Delete the type of the deleted objects, e.g. User{}. Delete then reads
+// each object before deleting it and calls its BeforeDelete hook, if the type implements
+// BeforeDeleter. Objects that are not found are deleted without calling the hook.
+func WithModel(model any) DeleteOpt {
+	return func(o *deleteOptions) {
+		o.model = model
+	}
+}
+
+// addCascadeEdges records the cascade predicates of the struct type t, and of the types
+// of its cascade edges, by node type.
+func addCascadeEdges(cascade map[string][]string, t reflect.Type) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	nodeType := dg.GetNodeType(reflect.New(t).Interface())
+	if _, ok := cascade[nodeType]; ok {
+		return
+	}
+	cascade[nodeType] = []string{}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || !slices.Contains(tagOptions(field.Tag.Get("dgraph")), cascadeTag) {
+			continue
+		}
+		cascade[nodeType] = append(cascade[nodeType], predicateName(field))
+		addCascadeEdges(cascade, field.Type)
+	}
+}
+
+// tagOptions returns the options of a dgraph tag, separated by commas or spaces.
+func tagOptions(tag string) []string {
+	return strings.FieldsFunc(tag, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// Delete implements removing objects with the specified UIDs.
+func (c client) Delete(ctx context.Context, uids []string, opts ...DeleteOpt) error {
+	return c.intercept(ctx, "Delete", uids, func(ctx context.Context) error {
+		return c.delete(ctx, nil, uids, opts)
+	})
+}
+
+func (c client) delete(ctx context.Context, tx *dg.TxnContext, uids []string, opts []DeleteOpt) error {
+	var options deleteOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.model != nil {
+		err := beforeDeleteHooks(ctx, options.model, uids, func(obj any, uid string) error {
+			err := c.get(ctx, tx, obj, uid)
+			if errors.Is(err, ErrNodeNotFound) {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if c.options.softDelete {
+		return c.softDelete(ctx, tx, uids, options)
+	}
+	if !options.inbound && len(options.cascade) == 0 {
+		return c.withTxn(ctx, tx, func(tx *dg.TxnContext) error {
+			return tx.DeleteNode(uids...)
+		})
+	}
+	if len(uids) == 0 {
+		return errors.New("uids cannot be empty")
+	}
+
+	// the nodes are read and deleted in the same transaction, so edges added
+	// concurrently make the commit fail instead of being left dangling
+	return c.withTxn(ctx, tx, func(txn *dg.TxnContext) error {
+		return c.deleteNodes(ctx, txn, tx == nil, uids, options)
+	})
+}
+
+// deleteNodes removes the nodes with the given UIDs in txn, along with the nodes they own
+// and the edges pointing to them if the options ask for it.
+func (c client) deleteNodes(ctx context.Context, txn *dg.TxnContext, commitNow bool, uids []string,
+	options deleteOptions) error {
+	deleted := uids
+	if len(options.cascade) > 0 {
+		var err error
+		if deleted, err = cascadeUIDs(ctx, txn, uids, options.cascade); err != nil {
+			return err
+		}
+	}
+	nquads := make([]*api.NQuad, 0, len(deleted))
+	for _, uid := range deleted {
+		nquads = append(nquads, &api.NQuad{
+			Subject:     uid,
+			Predicate:   deleteAllValues,
+			ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: deleteAllValues}},
+		})
+		// deleting all predicates only covers those of the node's types
+		if options.purge {
+			nquads = append(nquads, &api.NQuad{
+				Subject:     uid,
+				Predicate:   SoftDeletePredicate,
+				ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: deleteAllValues}},
+			})
+		}
+	}
+	nodeNQuads := len(nquads)
+	if options.inbound {
+		inbound, err := inboundEdges(ctx, txn, deleted)
+		if err != nil {
+			return err
+		}
+		nquads = append(nquads, inbound...)
+	}
+	c.logger.V(2).Info("Deleting nodes", "uidCount", len(deleted), "edgeCount", len(nquads)-nodeNQuads)
+	_, err := txn.Txn().Mutate(ctx, &api.Mutation{Del: nquads, CommitNow: commitNow})
+	return err
+}
+
+// uidNode is a node of a query result selecting only its UID
+type uidNode struct {
+	UID string `json:"uid"`
+}
+
+// cascadeUIDs returns uids and the UIDs of every node they own through cascade edges,
+// following them until no new nodes are found.
+func cascadeUIDs(ctx context.Context, tx *dg.TxnContext, uids []string,
+	cascade map[string][]string) ([]string, error) {
+	var predicates []string
+	for _, preds := range cascade {
+		for _, pred := range preds {
+			if !slices.Contains(predicates, pred) {
+				predicates = append(predicates, pred)
+			}
+		}
+	}
+	slices.Sort(predicates)
+
+	seen := make(map[string]bool, len(uids))
+	for _, uid := range uids {
+		seen[uid] = true
+	}
+	result := slices.Clone(uids)
+	frontier := uids
+	for len(frontier) > 0 && len(predicates) > 0 {
+		var sb strings.Builder
+		sb.WriteString("query q($uids: string) {\n  nodes(func: uid($uids)) {\n    uid\n    dgraph.type\n")
+		for i, pred := range predicates {
+			fmt.Fprintf(&sb, "    e%d: <%s> { uid }\n", i, pred)
+		}
+		sb.WriteString("  }\n}")
+
+		resp, err := tx.Txn().QueryWithVars(ctx, sb.String(), map[string]string{
+			"$uids": uidList(frontier),
+		})
+		if err != nil {
+			return nil, err
+		}
+		var data struct {
+			Nodes []map[string]json.RawMessage `json:"nodes"`
+		}
+		if err := json.Unmarshal(resp.Json, &data); err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for _, node := range data.Nodes {
+			var types []string
+			if raw, ok := node["dgraph.type"]; ok {
+				if err := json.Unmarshal(raw, &types); err != nil {
+					return nil, err
+				}
+			}
+			for i, pred := range predicates {
+				raw, ok := node[fmt.Sprintf("e%d", i)]
+				if !ok || !slices.ContainsFunc(types, func(t string) bool {
+					return slices.Contains(cascade[t], pred)
+				}) {
+					continue
+				}
+				var children []uidNode
+				if err := json.Unmarshal(raw, &children); err != nil {
+					// single (non-list) edges are returned as an object
+					var child uidNode
+					if err := json.Unmarshal(raw, &child); err != nil {
+						return nil, err
+					}
+					children = []uidNode{child}
+				}
+				for _, child := range children {
+					if child.UID != "" && !seen[child.UID] {
+						seen[child.UID] = true
+						result = append(result, child.UID)
+						frontier = append(frontier, child.UID)
+					}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// inboundEdges returns delete nquads for every edge of another node that points to one
+// of uids, found through the @reverse index of its predicate or by scanning the nodes of
+// the types declaring the predicate.
+func inboundEdges(ctx context.Context, tx *dg.TxnContext, uids []string) ([]*api.NQuad, error) {
+	resp, err := tx.Txn().Query(ctx, "schema {}")
+	if err != nil {
+		return nil, err
+	}
+	var schema struct {
+		Schema []*dg.Schema `json:"schema"`
+		Types  []struct {
+			Name   string `json:"name"`
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		} `json:"types"`
+	}
+	if err := json.Unmarshal(resp.Json, &schema); err != nil {
+		return nil, err
+	}
+	// declaring maps each predicate to the types declaring it
+	declaring := make(map[string][]string)
+	for _, t := range schema.Types {
+		for _, field := range t.Fields {
+			declaring[field.Name] = append(declaring[field.Name], t.Name)
+		}
+	}
+
+	// blocks maps the name of each query block to the predicate whose edges it finds
+	blocks := make(map[string]string)
+	var sb strings.Builder
+	sb.WriteString("query q($uids: string) {\n")
+	for _, s := range schema.Schema {
+		if s.Type != "uid" || strings.HasPrefix(s.Predicate, "dgraph.") {
+			continue
+		}
+		if s.Reverse {
+			name := fmt.Sprintf("in%d", len(blocks))
+			blocks[name] = s.Predicate
+			fmt.Fprintf(&sb, "  %s(func: uid($uids)) { uid from: <~%s> { uid } }\n", name, s.Predicate)
+			continue
+		}
+		for _, typeName := range declaring[s.Predicate] {
+			name := fmt.Sprintf("in%d", len(blocks))
+			blocks[name] = s.Predicate
+			fmt.Fprintf(&sb, "  %s(func: type(<%s>)) @cascade { uid to: <%s> @filter(uid($uids)) { uid } }\n",
+				name, typeName, s.Predicate)
+		}
+	}
+	sb.WriteString("}")
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	resp, err = tx.Txn().QueryWithVars(ctx, sb.String(), map[string]string{"$uids": uidList(uids)})
+	if err != nil {
+		return nil, err
+	}
+	type edge struct {
+		UID string `json:"uid"`
+	}
+	var data map[string][]struct {
+		UID  string `json:"uid"`
+		From []edge `json:"from"`
+		To   []edge `json:"to"`
+	}
+	if err := json.Unmarshal(resp.Json, &data); err != nil {
+		return nil, err
+	}
+
+	var nquads []*api.NQuad
+	for i := range len(blocks) {
+		name := fmt.Sprintf("in%d", i)
+		for _, node := range data[name] {
+			for _, from := range node.From {
+				nquads = append(nquads, &api.NQuad{Subject: from.UID, Predicate: blocks[name], ObjectId: node.UID})
+			}
+			for _, to := range node.To {
+				nquads = append(nquads, &api.NQuad{Subject: node.UID, Predicate: blocks[name], ObjectId: to.UID})
+			}
+		}
+	}
+	return nquads, nil
+}
+
+// uidList formats uids as a list for a DQL uid variable, e.g. [0x1, 0x2].
+func uidList(uids []string) string {
+	return "[" + strings.Join(uids, ", ") + "]"
+}
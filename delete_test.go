@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hypermodeinc/modusgraph"
 	"github.com/stretchr/testify/require"
 )
 
@@ -78,3 +79,273 @@ func TestClientDelete(t *testing.T) {
 		})
 	}
 }
+
+type DeleteNote struct {
+	Text string `json:"text,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type DeleteTask struct {
+	Title string        `json:"title,omitempty"`
+	Notes []*DeleteNote `json:"notes,omitempty" dgraph:"cascade"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type DeleteProject struct {
+	Name  string        `json:"name,omitempty" dgraph:"index=exact"`
+	Tasks []*DeleteTask `json:"tasks,omitempty" dgraph:"cascade"`
+	Owner *DeleteUser   `json:"owner,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type DeleteUser struct {
+	Name      string           `json:"name,omitempty" dgraph:"index=exact"`
+	Favorites []*DeleteProject `json:"favorites,omitempty" dgraph:"reverse"`
+	Watching  []*DeleteProject `json:"watching,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientDeleteCascade(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "DeleteCascadeWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "DeleteCascadeWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			user := DeleteUser{Name: "Owner"}
+			require.NoError(t, client.Insert(ctx, &user))
+
+			newProject := func(name string) *DeleteProject {
+				return &DeleteProject{
+					Name:  name,
+					Owner: &DeleteUser{UID: user.UID},
+					Tasks: []*DeleteTask{
+						{Title: name + " Task 1", Notes: []*DeleteNote{{Text: "Note 1"}, {Text: "Note 2"}}},
+						{Title: name + " Task 2"},
+					},
+				}
+			}
+			cascaded, kept := newProject("Cascaded"), newProject("Kept")
+			require.NoError(t, client.Insert(ctx, []*DeleteProject{cascaded, kept}))
+
+			user.Favorites = []*DeleteProject{{UID: cascaded.UID}, {UID: kept.UID}}
+			user.Watching = []*DeleteProject{{UID: cascaded.UID}, {UID: kept.UID}}
+			require.NoError(t, client.Update(ctx, &user))
+
+			err := client.Delete(ctx, []string{cascaded.UID},
+				modusgraph.WithCascade(DeleteProject{}), modusgraph.WithInboundEdges())
+			require.NoError(t, err, "Delete should succeed")
+
+			var tasks []DeleteTask
+			require.NoError(t, client.Query(ctx, DeleteTask{}).Nodes(&tasks))
+			require.Len(t, tasks, 2, "Owned tasks should be deleted")
+			for _, task := range tasks {
+				require.Contains(t, task.Title, "Kept")
+			}
+			var notes []DeleteNote
+			require.NoError(t, client.Query(ctx, DeleteNote{}).Nodes(&notes))
+			require.Len(t, notes, 2, "Notes owned through tasks should be deleted")
+
+			var owner DeleteUser
+			require.NoError(t, client.Get(ctx, &owner, user.UID))
+			require.Equal(t, "Owner", owner.Name, "Nodes that are not owned should be kept")
+			require.Len(t, owner.Favorites, 1, "Inbound edges of reverse predicates should be removed")
+			require.Equal(t, kept.UID, owner.Favorites[0].UID)
+			require.Len(t, owner.Watching, 1, "Inbound edges of other predicates should be removed")
+			require.Equal(t, kept.UID, owner.Watching[0].UID)
+
+			// without options, incoming edges are left in place
+			require.NoError(t, client.Delete(ctx, []string{kept.UID}))
+			owner = DeleteUser{}
+			require.NoError(t, client.Get(ctx, &owner, user.UID))
+			require.Len(t, owner.Watching, 1)
+			require.NoError(t, client.Query(ctx, DeleteTask{}).Nodes(&tasks))
+			require.Len(t, tasks, 2, "Tasks should only be deleted with WithCascade")
+		})
+	}
+}
+
+func TestClientSoftDelete(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "SoftDeleteWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "SoftDeleteWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri, modusgraph.WithSoftDelete(true))
+			defer cleanup()
+
+			ctx := context.Background()
+			user := DeleteUser{Name: "Watcher"}
+			require.NoError(t, client.Insert(ctx, &user))
+			project := &DeleteProject{
+				Name:  "Archived",
+				Tasks: []*DeleteTask{{Title: "Archived Task", Notes: []*DeleteNote{{Text: "Archived Note"}}}},
+			}
+			other := &DeleteProject{Name: "Active"}
+			require.NoError(t, client.Insert(ctx, []*DeleteProject{project, other}))
+			user.Watching = []*DeleteProject{{UID: project.UID}}
+			require.NoError(t, client.Update(ctx, &user))
+
+			require.NoError(t, client.Delete(ctx, []string{project.UID}, modusgraph.WithCascade(DeleteProject{})))
+
+			var got DeleteProject
+			require.Error(t, client.Get(ctx, &got, project.UID), "Soft deleted nodes should not be found")
+			var projects []DeleteProject
+			require.NoError(t, client.Query(ctx, DeleteProject{}).Nodes(&projects))
+			require.Len(t, projects, 1, "Soft deleted nodes should not be queried")
+			require.Equal(t, "Active", projects[0].Name)
+			tasks, err := modusgraph.Find[DeleteTask](ctx, client)
+			require.NoError(t, err)
+			require.Empty(t, tasks, "Owned nodes should be soft deleted with WithCascade")
+			found, err := modusgraph.Find[DeleteProject](ctx, client,
+				modusgraph.WithFilter("eq(name, $name)", map[string]string{"$name": "Archived"}))
+			require.NoError(t, err)
+			require.Empty(t, found, "Filters should be combined with the soft delete filter")
+			require.NoError(t, client.Query(ctx, DeleteProject{}).OrderAsc("name").
+				Filter(`eq(name, "Archived") AND NOT has(deletedAt)`).Nodes(&projects))
+			require.Empty(t, projects, "Query filters should be able to keep the soft delete filter")
+
+			// the data is still there for the retention period
+			purged, err := client.Purge(ctx, time.Hour)
+			require.NoError(t, err)
+			require.Zero(t, purged, "Recent deletions should not be purged")
+
+			require.NoError(t, client.Restore(ctx, []string{project.UID}, modusgraph.WithCascade(DeleteProject{})))
+			require.NoError(t, client.Get(ctx, &got, project.UID), "Restored nodes should be found")
+			require.Len(t, got.Tasks, 1)
+			require.Len(t, got.Tasks[0].Notes, 1)
+			tasks, err = modusgraph.Find[DeleteTask](ctx, client)
+			require.NoError(t, err)
+			require.Len(t, tasks, 1, "Owned nodes should be restored with WithCascade")
+
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err)
+			defer func() { _ = tx.Discard(ctx) }()
+			require.NoError(t, tx.Delete(ctx, []string{project.UID}, modusgraph.WithCascade(DeleteProject{})))
+			require.Error(t, tx.Get(ctx, &DeleteProject{}, project.UID), "The transaction should see its own soft delete")
+			require.NoError(t, tx.Commit(ctx))
+
+			purged, err = client.Purge(ctx, 0)
+			require.NoError(t, err)
+			require.Equal(t, 3, purged, "The project, its task and its note should be purged")
+
+			var watcher DeleteUser
+			require.NoError(t, client.Get(ctx, &watcher, user.UID))
+			require.Empty(t, watcher.Watching, "Edges to purged nodes should be removed")
+			result, err := client.QueryRaw(ctx, fmt.Sprintf(`{ q(func: uid(%s)) { uid name title deletedAt } }`,
+				project.UID), nil)
+			require.NoError(t, err)
+			require.JSONEq(t, fmt.Sprintf(`{"q":[{"uid":"%s"}]}`, project.UID), string(result),
+				"Purged nodes should have no predicates left")
+		})
+	}
+}
+
+type DeleteAccount struct {
+	Email string `json:"email,omitempty" dgraph:"index=exact unique upsert"`
+	Name  string `json:"name,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientSoftDeleteUnique(t *testing.T) {
+	// a remote Dgraph server enforces @unique itself, so only the embedded engine is tested
+	uri := "file://" + GetTempDir(t)
+	client, cleanup := CreateTestClient(t, uri, modusgraph.WithSoftDelete(true))
+	defer cleanup()
+
+	ctx := context.Background()
+	deleted := DeleteAccount{Email: "ada@example.com", Name: "Deleted"}
+	require.NoError(t, client.Upsert(ctx, &deleted), "Upsert should work before any node is soft deleted")
+	require.NoError(t, client.Delete(ctx, []string{deleted.UID}))
+
+	replacement := DeleteAccount{Email: "ada@example.com", Name: "Replacement"}
+	require.NoError(t, client.Insert(ctx, &replacement), "Soft deleted nodes should not hold unique values")
+	require.NotEqual(t, deleted.UID, replacement.UID)
+
+	var uniqueErr *modusgraph.UniqueError
+	err := client.Restore(ctx, []string{deleted.UID})
+	require.ErrorAs(t, err, &uniqueErr, "Restoring a node whose unique value was taken should fail")
+	require.Equal(t, replacement.UID, uniqueErr.UID)
+
+	require.NoError(t, client.Delete(ctx, []string{replacement.UID}))
+	upserted := DeleteAccount{Email: "ada@example.com", Name: "Upserted"}
+	require.NoError(t, client.Upsert(ctx, &upserted))
+	require.NotEqual(t, deleted.UID, upserted.UID, "Upsert should not match soft deleted nodes")
+	require.NotEqual(t, replacement.UID, upserted.UID, "Upsert should not match soft deleted nodes")
+
+	require.NoError(t, client.Delete(ctx, []string{upserted.UID}))
+	require.NoError(t, client.Restore(ctx, []string{deleted.UID}), "A free unique value should be claimed back")
+	var got DeleteAccount
+	require.NoError(t, client.Get(ctx, &got, deleted.UID))
+	require.Equal(t, "Deleted", got.Name)
+
+	// nodes with a deletedAt predicate keep their unique values for clients that don't
+	// soft delete nodes
+	plain, err := modusgraph.NewClient(uri, modusgraph.WithAutoSchema(true))
+	require.NoError(t, err)
+	defer plain.Close()
+	archived := ArchivedAccount{Email: "grace@example.com", DeletedAt: time.Now()}
+	require.NoError(t, plain.Insert(ctx, &archived))
+	err = plain.Insert(ctx, &ArchivedAccount{Email: "grace@example.com"})
+	require.ErrorAs(t, err, &uniqueErr, "Only clients created with WithSoftDelete should skip deleted nodes")
+	require.Equal(t, archived.UID, uniqueErr.UID)
+}
+
+type ArchivedAccount struct {
+	Email     string    `json:"email,omitempty" dgraph:"index=exact unique upsert"`
+	DeletedAt time.Time `json:"deletedAt,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
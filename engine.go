@@ -6,12 +6,17 @@
 package modusgraph
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"path"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,10 +32,12 @@ import (
 	"github.com/hypermodeinc/dgraph/v25/protos/pb"
 	"github.com/hypermodeinc/dgraph/v25/query"
 	"github.com/hypermodeinc/dgraph/v25/schema"
+	"github.com/hypermodeinc/dgraph/v25/types"
 	"github.com/hypermodeinc/dgraph/v25/worker"
 	"github.com/hypermodeinc/dgraph/v25/x"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
 )
 
 var (
@@ -39,11 +46,18 @@ var (
 	// activeEngine tracks the current Engine instance for global access
 	activeEngine *Engine
 
-	ErrSingletonOnly    = errors.New("only one instance of modusGraph can exist in a process")
-	ErrEmptyDataDir     = errors.New("data directory is required")
-	ErrClosedEngine     = errors.New("modusGraph engine is closed")
-	ErrNonExistentDB    = errors.New("namespace does not exist")
-	ErrInvalidCacheSize = errors.New("cache size must be zero or positive")
+	// postingCache is the posting list cache created for the engines with a cache of
+	// postingCacheSize bytes
+	postingCache     *posting.MemoryLayer
+	postingCacheSize int64
+
+	ErrSingletonOnly          = errors.New("only one instance of modusGraph can exist in a process")
+	ErrEmptyDataDir           = errors.New("data directory is required")
+	ErrClosedEngine           = errors.New("modusGraph engine is closed")
+	ErrNonExistentDB          = errors.New("namespace does not exist")
+	ErrDeleteDefaultNamespace = errors.New("default namespace cannot be deleted")
+	ErrInvalidCacheSize       = errors.New("cache size must be zero or positive")
+	ErrInvalidChangeRetention = errors.New("change retention must be zero or positive")
 )
 
 // Engine is an instance of modusGraph.
@@ -54,12 +68,23 @@ type Engine struct {
 
 	z *zero
 
+	// tracks open transactions and detects write conflicts between them
+	oracle *oracle
+
+	// collects the changes of transactions and delivers the committed ones to subscribers
+	feed *changeFeed
+
 	// points to default / 0 / galaxy namespace
 	db0 *Namespace
 
+	// directory the data of the engine is stored in
+	dataDir string
+
 	listener *bufconn.Listener
 	server   *grpc.Server
-	logger   logr.Logger
+	// connections of the Dgraph clients returned by getClient, by client
+	conns  sync.Map
+	logger logr.Logger
 }
 
 // NewEngine returns a new modusGraph instance.
@@ -95,10 +120,12 @@ func NewEngine(conf Config) (*Engine, error) {
 	worker.InitForLite(worker.State.Pstore)
 	schema.Init(worker.State.Pstore)
 	cacheSizeBytes := conf.cacheSizeMB * 1024 * 1024
-	posting.Init(worker.State.Pstore, int64(cacheSizeBytes), false)
+	initPosting(int64(cacheSizeBytes))
 
 	engine := &Engine{
-		logger: conf.logger,
+		dataDir: conf.dataDir,
+		logger:  conf.logger,
+		feed:    newChangeFeed(conf.changeRetention, conf.logger),
 	}
 	engine.isOpen.Store(true)
 	engine.logger.V(1).Info("Initializing engine state")
@@ -106,6 +133,10 @@ func NewEngine(conf Config) (*Engine, error) {
 		engine.logger.Error(err, "Failed to reset database")
 		return nil, fmt.Errorf("error resetting db: %w", err)
 	}
+	if err := engine.feed.open(engine.z.readTs()); err != nil {
+		engine.logger.Error(err, "Failed to open change log")
+		return nil, fmt.Errorf("error opening change log: %w", err)
+	}
 	// Store the engine as the active instance
 	activeEngine = engine
 	x.UpdateHealthStatus(true)
@@ -116,6 +147,19 @@ func NewEngine(conf Config) (*Engine, error) {
 	return engine, nil
 }
 
+// initPosting initializes the posting lists of Dgraph with a cache of cacheSize bytes.
+// Dgraph never releases the caches it creates, so engines with the same cache size reuse
+// the cache of the first one.
+func initPosting(cacheSize int64) {
+	if postingCache == nil || postingCacheSize != cacheSize {
+		posting.Init(worker.State.Pstore, cacheSize, false)
+		postingCache, postingCacheSize = posting.MemLayerInstance, cacheSize
+		return
+	}
+	posting.Init(worker.State.Pstore, 0, false)
+	posting.MemLayerInstance = postingCache
+}
+
 // Shutdown closes the active Engine instance and resets the singleton state.
 func Shutdown() {
 	if activeEngine != nil {
@@ -127,22 +171,43 @@ func Shutdown() {
 }
 
 func (engine *Engine) GetClient() (*dgo.Dgraph, error) {
-	engine.logger.V(2).Info("Getting Dgraph client from engine")
-	client, err := createDgraphClient(context.Background(), engine.listener)
+	return engine.getClient(engine.db0)
+}
+
+// getClient returns a Dgraph client whose requests are scoped to the given namespace,
+// and go through the given interceptors.
+func (engine *Engine) getClient(ns *Namespace, interceptors ...grpc.UnaryClientInterceptor) (*dgo.Dgraph, error) {
+	engine.logger.V(2).Info("Getting Dgraph client from engine", "namespaceID", ns.ID())
+	interceptors = append([]grpc.UnaryClientInterceptor{namespaceInterceptor(ns.ID())}, interceptors...)
+	client, conn, err := createDgraphClient(context.Background(), engine.listener,
+		grpc.WithChainUnaryInterceptor(interceptors...))
 	if err != nil {
 		engine.logger.Error(err, "Failed to create Dgraph client")
+		return nil, err
+	}
+	engine.conns.Store(client, conn)
+	return client, nil
+}
+
+// closeClient closes the connection of a Dgraph client returned by getClient.
+func (engine *Engine) closeClient(client *dgo.Dgraph) {
+	if conn, ok := engine.conns.LoadAndDelete(client); ok {
+		_ = conn.(*grpc.ClientConn).Close()
 	}
-	return client, err
 }
 
 func (engine *Engine) CreateNamespace() (*Namespace, error) {
-	engine.mutex.RLock()
-	defer engine.mutex.RUnlock()
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
 
 	if !engine.isOpen.Load() {
 		return nil, ErrClosedEngine
 	}
 
+	return engine.createNamespaceWithLock()
+}
+
+func (engine *Engine) createNamespaceWithLock() (*Namespace, error) {
 	startTs, err := engine.z.nextTs()
 	if err != nil {
 		return nil, err
@@ -162,6 +227,23 @@ func (engine *Engine) CreateNamespace() (*Namespace, error) {
 	return &Namespace{id: nsID, engine: engine}, nil
 }
 
+// ensureNamespace returns the namespace with the given ID. As namespace IDs are
+// allocated sequentially, the namespace is created if nsID is the next ID to be
+// allocated, any other ID must belong to an existing namespace.
+func (engine *Engine) ensureNamespace(nsID uint64) (*Namespace, error) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return nil, ErrClosedEngine
+	}
+
+	if nsID == engine.z.lastNamespace+1 {
+		return engine.createNamespaceWithLock()
+	}
+	return engine.getNamespaceWithLock(nsID)
+}
+
 func (engine *Engine) GetNamespace(nsID uint64) (*Namespace, error) {
 	engine.mutex.RLock()
 	defer engine.mutex.RUnlock()
@@ -174,15 +256,78 @@ func (engine *Engine) getNamespaceWithLock(nsID uint64) (*Namespace, error) {
 		return nil, ErrClosedEngine
 	}
 
-	if nsID > engine.z.lastNamespace {
+	if nsID > engine.z.lastNamespace || engine.z.isDeletedNamespace(nsID) {
 		return nil, ErrNonExistentDB
 	}
 
-	// TODO: when delete namespace is implemented, check if the namespace exists
-
 	return &Namespace{id: nsID, engine: engine}, nil
 }
 
+// ListNamespaces returns all namespaces that haven't been deleted, ordered by ID.
+func (engine *Engine) ListNamespaces() ([]*Namespace, error) {
+	engine.mutex.RLock()
+	defer engine.mutex.RUnlock()
+
+	if !engine.isOpen.Load() {
+		return nil, ErrClosedEngine
+	}
+
+	var namespaces []*Namespace
+	for nsID := uint64(0); nsID <= engine.z.lastNamespace; nsID++ {
+		if !engine.z.isDeletedNamespace(nsID) {
+			namespaces = append(namespaces, &Namespace{id: nsID, engine: engine})
+		}
+	}
+	return namespaces, nil
+}
+
+// DeleteNamespace drops all the data and schema of the namespace and deletes it.
+// The ID of a deleted namespace is never reused. The default namespace can't be deleted.
+func (engine *Engine) DeleteNamespace(ctx context.Context, nsID uint64) error {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return ErrClosedEngine
+	}
+	if nsID == 0 {
+		return ErrDeleteDefaultNamespace
+	}
+	if _, err := engine.getNamespaceWithLock(nsID); err != nil {
+		return err
+	}
+
+	if err := engine.dropAllInNsWithLock(ctx, nsID); err != nil {
+		return err
+	}
+	return engine.z.deleteNamespace(nsID)
+}
+
+// dropAllInNs drops all the data and schema of the namespace, leaving the other
+// namespaces intact.
+func (engine *Engine) dropAllInNs(ctx context.Context, ns *Namespace) error {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return ErrClosedEngine
+	}
+
+	return engine.dropAllInNsWithLock(ctx, ns.ID())
+}
+
+func (engine *Engine) dropAllInNsWithLock(ctx context.Context, nsID uint64) error {
+	p := &pb.Proposal{Mutations: &pb.Mutations{
+		GroupId:   1,
+		DropOp:    pb.Mutations_ALL_IN_NS,
+		DropValue: strconv.FormatUint(nsID, 10),
+	}}
+	if err := worker.ApplyMutations(ctx, p); err != nil {
+		return fmt.Errorf("error applying mutation: %w", err)
+	}
+	return nil
+}
+
 func (engine *Engine) GetDefaultNamespace() *Namespace {
 	return engine.db0
 }
@@ -206,6 +351,7 @@ func (engine *Engine) DropAll(ctx context.Context) error {
 	if err := engine.reset(); err != nil {
 		return fmt.Errorf("error resetting db: %w", err)
 	}
+	engine.feed.reset(engine.z.readTs())
 
 	// TODO: insert drop record
 	return nil
@@ -228,12 +374,82 @@ func (engine *Engine) dropData(ctx context.Context, ns *Namespace) error {
 	if err := worker.ApplyMutations(ctx, p); err != nil {
 		return fmt.Errorf("error applying mutation: %w", err)
 	}
+	// the zero state is stored with the data of the default namespace
+	if ns.ID() == 0 {
+		if err := engine.z.writeZeroState(); err != nil {
+			return fmt.Errorf("error restoring zero state: %w", err)
+		}
+	}
 
 	// TODO: insert drop record
 	// TODO: should we reset back the timestamp as well?
 	return nil
 }
 
+func (engine *Engine) dropPredicate(ctx context.Context, ns *Namespace, pred string) error {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return ErrClosedEngine
+	}
+
+	attr := x.NamespaceAttr(ns.ID(), pred)
+	if x.IsPreDefinedPredicate(attr) {
+		return fmt.Errorf("predicate %s is pre-defined and is not allowed to be dropped", pred)
+	}
+
+	startTs, err := engine.z.nextTs()
+	if err != nil {
+		return err
+	}
+
+	// an edge without subject and with a star value drops the whole predicate
+	p := &pb.Proposal{Mutations: &pb.Mutations{
+		GroupId: 1,
+		StartTs: startTs,
+		Edges: []*pb.DirectedEdge{{
+			Attr:  attr,
+			Value: []byte(x.Star),
+			Op:    pb.DirectedEdge_DEL,
+		}},
+	}, StartTs: startTs}
+	if err := worker.ApplyMutations(ctx, p); err != nil {
+		return fmt.Errorf("error applying mutation: %w", err)
+	}
+	return nil
+}
+
+func (engine *Engine) dropType(ctx context.Context, ns *Namespace, typeName string) error {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return ErrClosedEngine
+	}
+
+	attr := x.NamespaceAttr(ns.ID(), typeName)
+	if x.IsPreDefinedType(attr) {
+		return fmt.Errorf("type %s is pre-defined and is not allowed to be dropped", typeName)
+	}
+
+	startTs, err := engine.z.nextTs()
+	if err != nil {
+		return err
+	}
+
+	p := &pb.Proposal{Mutations: &pb.Mutations{
+		GroupId:   1,
+		StartTs:   startTs,
+		DropOp:    pb.Mutations_TYPE,
+		DropValue: attr,
+	}, StartTs: startTs}
+	if err := worker.ApplyMutations(ctx, p); err != nil {
+		return fmt.Errorf("error applying mutation: %w", err)
+	}
+	return nil
+}
+
 func (engine *Engine) alterSchema(ctx context.Context, ns *Namespace, sch string) error {
 	engine.mutex.Lock()
 	defer engine.mutex.Unlock()
@@ -250,9 +466,17 @@ func (engine *Engine) alterSchema(ctx context.Context, ns *Namespace, sch string
 }
 
 func (engine *Engine) alterSchemaWithParsed(ctx context.Context, sc *schema.ParsedSchema) error {
+	// Predicates whose schema is unchanged are skipped, as altering a predicate fails
+	// while a transaction has pending writes to it.
+	preds := sc.Preds[:0]
 	for _, pred := range sc.Preds {
+		if current, ok := schema.State().Get(ctx, pred.Predicate); ok && proto.Equal(&current, pred) {
+			continue
+		}
 		worker.InitTablet(pred.Predicate)
+		preds = append(preds, pred)
 	}
+	sc.Preds = preds
 
 	startTs, err := engine.z.nextTs()
 	if err != nil {
@@ -285,27 +509,279 @@ func (engine *Engine) queryWithLock(ctx context.Context,
 	ns *Namespace,
 	q string,
 	vars map[string]string) (*api.Response, error) {
+	return engine.queryAtWithLock(ctx, ns, 0, q, vars)
+}
+
+// queryAt performs a read-only query in the snapshot at readTs, or in the latest
+// snapshot if readTs is zero. The response carries the timestamp it was read at, so
+// that a read-only transaction keeps reading from the same snapshot.
+func (engine *Engine) queryAt(ctx context.Context,
+	ns *Namespace,
+	readTs uint64,
+	q string,
+	vars map[string]string) (*api.Response, error) {
+	engine.mutex.RLock()
+	defer engine.mutex.RUnlock()
+
+	return engine.queryAtWithLock(ctx, ns, readTs, q, vars)
+}
+
+func (engine *Engine) queryAtWithLock(ctx context.Context,
+	ns *Namespace,
+	readTs uint64,
+	q string,
+	vars map[string]string) (*api.Response, error) {
+	if !engine.isOpen.Load() {
+		return nil, ErrClosedEngine
+	}
+	if readTs == 0 {
+		readTs = engine.z.readTs()
+	}
+
+	// Best effort disables the transaction cache, so that reading at a timestamp that
+	// is also the start of a pending transaction doesn't observe its uncommitted writes.
+	resp, err := engine.doQuery(ctx, ns, &api.Request{
+		ReadOnly:   true,
+		BestEffort: true,
+		Query:      q,
+		StartTs:    readTs,
+		Vars:       vars,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Txn = &api.TxnContext{StartTs: readTs}
+	return resp, nil
+}
+
+// queryTxn performs a query in the snapshot of the transaction started at startTs,
+// which includes the pending writes of that transaction.
+func (engine *Engine) queryTxn(ctx context.Context,
+	ns *Namespace,
+	startTs uint64,
+	q string,
+	vars map[string]string) (*api.Response, error) {
+	engine.mutex.RLock()
+	defer engine.mutex.RUnlock()
+
 	if !engine.isOpen.Load() {
 		return nil, ErrClosedEngine
 	}
+	if !engine.oracle.isOpen(startTs) {
+		return nil, dgo.ErrAborted
+	}
 
-	engine.logger.V(2).Info("Querying namespace", "namespaceID", ns.ID(), "query", q)
+	return engine.doQuery(ctx, ns, &api.Request{
+		Query:   q,
+		StartTs: startTs,
+		Vars:    vars,
+	})
+}
+
+func (engine *Engine) doQuery(ctx context.Context, ns *Namespace, req *api.Request) (*api.Response, error) {
+	engine.logger.V(2).Info("Querying namespace", "namespaceID", ns.ID(), "startTs", req.StartTs, "query", req.Query)
 	ctx = x.AttachNamespace(ctx, ns.ID())
-	return (&edgraph.Server{}).QueryNoAuth(ctx, &api.Request{
-		ReadOnly: true,
-		Query:    q,
-		StartTs:  engine.z.readTs(),
-		Vars:     vars,
+	resp, err := (&edgraph.Server{}).QueryNoAuth(ctx, req)
+	if err != nil || !strings.HasPrefix(strings.TrimSpace(req.Query), "schema") {
+		return resp, err
+	}
+	if err := addSchemaNodes(ctx, ns, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// defaultSchemaFields are the fields of each predicate a schema query returns by default
+var defaultSchemaFields = []string{"type", "index", "tokenizer", "reverse", "count", "list", "upsert",
+	"unique", "lang", "noconflict", "vector_specs"}
+
+// addSchemaNodes adds every predicate to the response of a schema query that doesn't name
+// any. Dgraph lists them per group known to the cluster, and the embedded engine has none,
+// so they are read from the local schema state instead.
+func addSchemaNodes(ctx context.Context, ns *Namespace, req *api.Request, resp *api.Response) error {
+	parsed, err := dql.Parse(dql.Request{Str: req.Query, Variables: req.Vars})
+	if err != nil {
+		return err
+	}
+	sr := parsed.Schema
+	if sr == nil || len(sr.Predicates) > 0 || len(sr.Types) > 0 {
+		return nil
+	}
+
+	fields := sr.Fields
+	if len(fields) == 0 {
+		fields = defaultSchemaFields
+	}
+	var nodes []*pb.SchemaNode
+	for _, attr := range schema.State().Predicates() {
+		if x.ParseNamespace(attr) != ns.ID() {
+			continue
+		}
+		if node := schemaNode(ctx, attr, fields); node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	slices.SortFunc(nodes, func(a, b *pb.SchemaNode) int {
+		return strings.Compare(a.Predicate, b.Predicate)
 	})
+
+	result := make(map[string]json.RawMessage)
+	if len(resp.Json) > 0 {
+		if err := json.Unmarshal(resp.Json, &result); err != nil {
+			return err
+		}
+	}
+	if _, ok := result["schema"]; ok {
+		return nil
+	}
+	if result["schema"], err = json.Marshal(nodes); err != nil {
+		return err
+	}
+	resp.Json, err = json.Marshal(result)
+	return err
+}
+
+// schemaNode returns the asked fields of the schema of the namespaced predicate attr,
+// or nil if it has no schema.
+func schemaNode(ctx context.Context, attr string, fields []string) *pb.SchemaNode {
+	typ, err := schema.State().TypeOf(attr)
+	if err != nil {
+		return nil
+	}
+	pred, _ := schema.State().Get(ctx, attr)
+	node := &pb.SchemaNode{Predicate: x.ParseAttr(attr)}
+	for _, field := range fields {
+		switch field {
+		case "type":
+			node.Type = typ.Name()
+		case "index":
+			node.Index = len(pred.GetTokenizer()) > 0
+		case "tokenizer":
+			if len(pred.GetTokenizer()) > 0 {
+				node.Tokenizer = schema.State().TokenizerNames(ctx, attr)
+			}
+			if len(pred.GetIndexSpecs()) > 0 {
+				node.Tokenizer = schema.State().VectorIndexes(ctx, attr)
+			}
+		case "reverse":
+			node.Reverse = pred.GetDirective() == pb.SchemaUpdate_REVERSE
+		case "count":
+			node.Count = pred.GetCount()
+		case "list":
+			node.List = pred.GetList()
+		case "upsert":
+			node.Upsert = pred.GetUpsert()
+		case "unique":
+			node.Unique = pred.GetUnique()
+		case "lang":
+			node.Lang = pred.GetLang()
+		case "noconflict":
+			node.NoConflict = pred.GetNoConflict()
+		case "vector_specs":
+			node.IndexSpecs = pred.GetIndexSpecs()
+		}
+	}
+	return node
+}
+
+// startReadTxn begins a new transaction for a query and returns its start timestamp.
+// The transaction only holds back the purge of conflict keys once it writes.
+func (engine *Engine) startReadTxn(ctx context.Context) (uint64, error) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return 0, ErrClosedEngine
+	}
+	startTs, err := engine.nextStartTsWithLock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	engine.oracle.beginRead(startTs)
+	return startTs, nil
+}
+
+func (engine *Engine) startTxnWithLock(ctx context.Context) (uint64, error) {
+	startTs, err := engine.nextStartTsWithLock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	engine.oracle.begin(startTs)
+	return startTs, nil
+}
+
+func (engine *Engine) nextStartTsWithLock(ctx context.Context) (uint64, error) {
+	for _, startTs := range engine.oracle.expired() {
+		engine.logger.V(1).Info("Aborting expired transaction", "startTs", startTs)
+		if err := engine.abortWithLock(ctx, startTs); err != nil {
+			return 0, err
+		}
+	}
+	return engine.z.nextTs()
 }
 
 func (engine *Engine) mutate(ctx context.Context, ns *Namespace, ms []*api.Mutation) (map[string]uint64, error) {
+	uids, _, err := engine.mutateTxn(ctx, ns, 0, ms, true)
+	return uids, err
+}
+
+// mutateTxn applies the mutations in the transaction started at startTs. If startTs
+// is zero, a new transaction is started. The mutations stay pending until the
+// transaction is committed, which happens right away if commitNow is set.
+func (engine *Engine) mutateTxn(ctx context.Context, ns *Namespace, startTs uint64,
+	ms []*api.Mutation, commitNow bool) (map[string]uint64, *api.TxnContext, error) {
 	if len(ms) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	engine.mutex.Lock()
 	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return nil, nil, ErrClosedEngine
+	}
+
+	if startTs == 0 {
+		var err error
+		if startTs, err = engine.startTxnWithLock(ctx); err != nil {
+			return nil, nil, err
+		}
+	} else if !engine.oracle.write(startTs) {
+		return nil, nil, dgo.ErrAborted
+	}
+
+	uids, err := engine.mutateWithLock(ctx, ns, startTs, ms)
+	if err != nil {
+		if commitNow {
+			if abortErr := engine.abortWithLock(ctx, startTs); abortErr != nil {
+				engine.logger.Error(abortErr, "Failed to abort transaction", "startTs", startTs)
+			}
+		}
+		return nil, nil, err
+	}
+
+	tc := &api.TxnContext{StartTs: startTs}
+	if commitNow {
+		if tc.CommitTs, err = engine.commitWithLock(ctx, startTs); err != nil {
+			return nil, nil, err
+		}
+	}
+	return uids, tc, nil
+}
+
+func (engine *Engine) mutateWithLock(ctx context.Context, ns *Namespace, startTs uint64,
+	ms []*api.Mutation) (map[string]uint64, error) {
+	dms, err := parseMutations(ms)
+	if err != nil {
+		return nil, err
+	}
+	return engine.mutateDqlWithLock(ctx, ns, startTs, dms)
+}
+
+func parseMutations(ms []*api.Mutation) ([]*dql.Mutation, error) {
 	dms := make([]*dql.Mutation, 0, len(ms))
 	for _, mu := range ms {
 		dm, err := edgraph.ParseMutationObject(mu, false)
@@ -314,6 +790,13 @@ func (engine *Engine) mutate(ctx context.Context, ns *Namespace, ms []*api.Mutat
 		}
 		dms = append(dms, dm)
 	}
+	return dms, nil
+}
+
+// mutateDqlWithLock assigns UIDs to the blank nodes of the mutations and applies them
+// in the transaction started at startTs.
+func (engine *Engine) mutateDqlWithLock(ctx context.Context, ns *Namespace, startTs uint64,
+	dms []*dql.Mutation) (map[string]uint64, error) {
 	newUids, err := query.ExtractBlankUIDs(ctx, dms)
 	if err != nil {
 		return nil, err
@@ -333,30 +816,241 @@ func (engine *Engine) mutate(ctx context.Context, ns *Namespace, ms []*api.Mutat
 		}
 	}
 
-	return engine.mutateWithDqlMutation(ctx, ns, dms, newUids)
+	if err := engine.verifyUniqueWithLock(ctx, ns, startTs, dms, newUids); err != nil {
+		return nil, err
+	}
+
+	return engine.mutateWithDqlMutation(ctx, ns, startTs, dms, newUids)
 }
 
-func (engine *Engine) mutateWithDqlMutation(ctx context.Context, ns *Namespace, dms []*dql.Mutation,
-	newUids map[string]uint64) (map[string]uint64, error) {
-	edges, err := query.ToDirectedEdges(dms, newUids)
+// verifyUniqueWithLock checks that the mutations don't set a value of a @unique
+// predicate on a node while another node has it, either in the snapshot of the
+// transaction started at startTs or in the mutations themselves. A node keeps its
+// value unless the mutations delete or replace it. For the requests of clients created
+// with WithSoftDelete, nodes marked as deleted don't hold their values, which they claim
+// again when the mutations restore them.
+func (engine *Engine) verifyUniqueWithLock(ctx context.Context, ns *Namespace, startTs uint64,
+	dms []*dql.Mutation, newUids map[string]uint64) error {
+
+	deletedPredicate := softDeletePredicate(ctx)
+
+	type uniqueValue struct {
+		predicate string
+		value     string
+	}
+	type nodePredicate struct {
+		uid       uint64
+		predicate string
+	}
+	subjectUID := func(subject string) (uint64, error) {
+		if uid, ok := newUids[subject]; ok {
+			return uid, nil
+		}
+		return dql.ParseUid(subject)
+	}
+
+	var values []uniqueValue
+	owners := make(map[uniqueValue]uint64)
+	written := make(map[nodePredicate]bool)
+	nodeTypes := make(map[uint64]string)
+	var restored []uint64
+	for _, dm := range dms {
+		for i, nq := range slices.Concat(dm.Del, dm.Set) {
+			if nq.ObjectId != "" || nq.ObjectValue == nil {
+				continue
+			}
+			if deletedPredicate != "" && nq.Predicate == deletedPredicate && i < len(dm.Del) {
+				if uid, err := subjectUID(nq.Subject); err == nil {
+					restored = append(restored, uid)
+				}
+				continue
+			}
+			if nq.Predicate == "dgraph.type" && i >= len(dm.Del) {
+				if uid, err := subjectUID(nq.Subject); err == nil {
+					nodeTypes[uid] = fmt.Sprintf("%v", dql.TypeValFrom(nq.ObjectValue).Value)
+				}
+				continue
+			}
+			su, ok := schema.State().Get(ctx, x.NamespaceAttr(ns.ID(), nq.Predicate))
+			if !ok || !su.Unique {
+				continue
+			}
+			uid, err := subjectUID(nq.Subject)
+			if err != nil {
+				return err
+			}
+			written[nodePredicate{uid, nq.Predicate}] = true
+			if i < len(dm.Del) {
+				continue
+			}
+
+			key := uniqueValue{nq.Predicate, fmt.Sprintf("%v", dql.TypeValFrom(nq.ObjectValue).Value)}
+			if owner, ok := owners[key]; ok && owner != uid {
+				return &UniqueError{NodeType: nodeTypes[owner], Field: key.predicate, Value: key.value,
+					UID: fmt.Sprintf("%#x", owner)}
+			} else if !ok {
+				owners[key] = uid
+				values = append(values, key)
+			}
+		}
+	}
+
+	// restored nodes claim the unique values they hold but don't write
+	claimed, err := engine.uniqueValuesWithLock(ctx, ns, startTs, restored)
 	if err != nil {
-		return nil, fmt.Errorf("error converting to directed edges: %w", err)
+		return err
+	}
+	for _, c := range claimed {
+		key := uniqueValue{c.predicate, c.value}
+		if written[nodePredicate{c.uid, c.predicate}] {
+			continue
+		}
+		if owner, ok := owners[key]; ok && owner != c.uid {
+			return &UniqueError{NodeType: nodeTypes[owner], Field: key.predicate, Value: key.value,
+				UID: fmt.Sprintf("%#x", owner)}
+		} else if !ok {
+			owners[key] = c.uid
+			values = append(values, key)
+		}
+	}
+	if len(values) == 0 {
+		return nil
 	}
-	ctx = x.AttachNamespace(ctx, ns.ID())
 
-	if !engine.isOpen.Load() {
-		return nil, ErrClosedEngine
+	var q strings.Builder
+	vars := make(map[string]string, len(values))
+	varDecls := make([]string, len(values))
+	for i, v := range values {
+		varDecls[i] = fmt.Sprintf("$v%d: string", i)
+		vars[fmt.Sprintf("$v%d", i)] = v.value
 	}
+	// querying a predicate missing from the schema fails
+	filter := ""
+	if deletedPredicate != "" {
+		if _, ok := schema.State().Get(ctx, x.NamespaceAttr(ns.ID(), deletedPredicate)); ok {
+			filter = fmt.Sprintf(" @filter(NOT has(<%s>))", deletedPredicate)
+		}
+	}
+	q.WriteString("query unique(" + strings.Join(varDecls, ", ") + ") {\n")
+	for i, v := range values {
+		q.WriteString(fmt.Sprintf("  u%d(func: eq(<%s>, $v%d))%s { uid dgraph.type }\n", i, v.predicate, i, filter))
+	}
+	q.WriteString("}")
 
-	startTs, err := engine.z.nextTs()
+	resp, err := engine.doQuery(ctx, ns, &api.Request{Query: q.String(), StartTs: startTs, Vars: vars})
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("error checking unique predicates: %w", err)
 	}
-	commitTs, err := engine.z.nextTs()
+	var result map[string][]struct {
+		UID   string   `json:"uid"`
+		DType []string `json:"dgraph.type"`
+	}
+	if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+		return err
+	}
+
+	for i, v := range values {
+		for _, node := range result[fmt.Sprintf("u%d", i)] {
+			uid, err := dql.ParseUid(node.UID)
+			if err != nil {
+				return err
+			}
+			if uid == owners[v] || written[nodePredicate{uid, v.predicate}] {
+				continue
+			}
+			uniqueErr := &UniqueError{Field: v.predicate, Value: v.value, UID: node.UID}
+			if len(node.DType) > 0 {
+				uniqueErr.NodeType = node.DType[0]
+			}
+			return uniqueErr
+		}
+	}
+	return nil
+}
+
+// nodeUniqueValue is a value of a @unique predicate held by a node
+type nodeUniqueValue struct {
+	uid       uint64
+	predicate string
+	value     string
+}
+
+// uniqueValuesWithLock returns the values of the @unique predicates of the nodes with
+// the given UIDs, in the snapshot of the transaction started at startTs.
+func (engine *Engine) uniqueValuesWithLock(ctx context.Context, ns *Namespace, startTs uint64,
+	uids []uint64) ([]nodeUniqueValue, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	var predicates []string
+	for _, attr := range schema.State().Predicates() {
+		if x.ParseNamespace(attr) != ns.ID() {
+			continue
+		}
+		if su, ok := schema.State().Get(ctx, attr); ok && su.Unique {
+			predicates = append(predicates, x.ParseAttr(attr))
+		}
+	}
+	if len(predicates) == 0 {
+		return nil, nil
+	}
+	slices.Sort(predicates)
+
+	ids := make([]string, len(uids))
+	for i, uid := range uids {
+		ids[i] = fmt.Sprintf("%#x", uid)
+	}
+	var q strings.Builder
+	q.WriteString("{\n  nodes(func: uid(" + strings.Join(ids, ", ") + ")) {\n    uid\n")
+	for _, pred := range predicates {
+		q.WriteString("    <" + pred + ">\n")
+	}
+	q.WriteString("  }\n}")
+
+	resp, err := engine.doQuery(ctx, ns, &api.Request{Query: q.String(), StartTs: startTs})
 	if err != nil {
+		return nil, fmt.Errorf("error reading unique predicates: %w", err)
+	}
+	var result struct {
+		Nodes []map[string]any `json:"nodes"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(resp.GetJson()))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
 		return nil, err
 	}
 
+	var values []nodeUniqueValue
+	for _, node := range result.Nodes {
+		id, _ := node["uid"].(string)
+		uid, err := dql.ParseUid(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, pred := range predicates {
+			switch v := node[pred].(type) {
+			case string, json.Number, bool:
+				values = append(values, nodeUniqueValue{uid, pred, fmt.Sprintf("%v", v)})
+			}
+		}
+	}
+	return values, nil
+}
+
+// mutateWithDqlMutation applies the mutations as pending writes of the transaction
+// started at startTs. They become visible to other readers once the transaction commits.
+func (engine *Engine) mutateWithDqlMutation(ctx context.Context, ns *Namespace, startTs uint64,
+	dms []*dql.Mutation, newUids map[string]uint64) (map[string]uint64, error) {
+	edges, err := query.ToDirectedEdges(dms, newUids)
+	if err != nil {
+		return nil, fmt.Errorf("error converting to directed edges: %w", err)
+	}
+	ctx = x.AttachNamespace(ctx, ns.ID())
+
+	if !engine.isOpen.Load() {
+		return nil, ErrClosedEngine
+	}
+
 	m := &pb.Mutations{
 		GroupId: 1,
 		StartTs: startTs,
@@ -372,13 +1066,280 @@ func (engine *Engine) mutateWithDqlMutation(ctx context.Context, ns *Namespace,
 		worker.InitTablet(edge.Attr)
 	}
 
+	// the previous values are only read if the changes can be logged or delivered
+	var changes []changeRecord
+	capturing := engine.feed.capturing(startTs)
+	if capturing {
+		if changes, err = pendingChanges(startTs, m.Edges); err != nil {
+			return nil, fmt.Errorf("error reading previous values: %w", err)
+		}
+	}
+
 	p := &pb.Proposal{Mutations: m, StartTs: startTs}
 	if err := worker.ApplyMutations(ctx, p); err != nil {
+		if errors.Is(err, x.ErrConflict) {
+			return nil, dgo.ErrAborted
+		}
 		return nil, err
 	}
+	// applying the edges converted their values to the types of the schema
+	if capturing {
+		engine.feed.record(startTs, setChangeValues(changes))
+	}
+	return newUids, nil
+}
+
+// upsertTxn runs an upsert block in the transaction started at startTs, or in a new
+// one if startTs is zero. The query is evaluated in the snapshot of the transaction,
+// uid(v) in the mutations is replaced by the UIDs of the query variable v, or by a new
+// node if v is empty, and mutations whose @if condition doesn't hold are skipped. The
+// query and the mutations run under the write lock, so that no other write can happen
+// between them. The response holds the JSON result of the query.
+func (engine *Engine) upsertTxn(ctx context.Context, ns *Namespace, startTs uint64,
+	q string, vars map[string]string, ms []*api.Mutation,
+	commitNow bool) (*api.Response, map[string]uint64, error) {
+
+	dms, err := parseMutations(ms)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return nil, nil, ErrClosedEngine
+	}
+
+	if startTs == 0 {
+		if startTs, err = engine.startTxnWithLock(ctx); err != nil {
+			return nil, nil, err
+		}
+	} else if !engine.oracle.write(startTs) {
+		return nil, nil, dgo.ErrAborted
+	}
+
+	resp, uids, err := engine.upsertWithLock(ctx, ns, startTs, q, vars, dms)
+	if err != nil {
+		if commitNow {
+			if abortErr := engine.abortWithLock(ctx, startTs); abortErr != nil {
+				engine.logger.Error(abortErr, "Failed to abort transaction", "startTs", startTs)
+			}
+		}
+		return nil, nil, err
+	}
+
+	resp.Txn = &api.TxnContext{StartTs: startTs}
+	if commitNow {
+		if posting.Oracle().GetTxn(startTs) == nil {
+			// no condition held and nothing was written before, so there is nothing to commit
+			engine.oracle.done(startTs)
+			engine.feed.discard(startTs)
+			return resp, uids, nil
+		}
+		if resp.Txn.CommitTs, err = engine.commitWithLock(ctx, startTs); err != nil {
+			return nil, nil, err
+		}
+	}
+	return resp, uids, nil
+}
+
+func (engine *Engine) upsertWithLock(ctx context.Context, ns *Namespace, startTs uint64,
+	q string, vars map[string]string, dms []*dql.Mutation) (*api.Response, map[string]uint64, error) {
+
+	uidVars := make(map[string][]string)
+	for _, dm := range dms {
+		for _, nq := range slices.Concat(dm.Set, dm.Del) {
+			for _, id := range []string{nq.Subject, nq.ObjectId} {
+				if strings.HasPrefix(id, "val(") {
+					return nil, nil, fmt.Errorf("val() is not supported in upsert mutations: %s", id)
+				}
+				if strings.HasPrefix(id, "uid(") {
+					uidVars[id[4:len(id)-1]] = nil
+				}
+			}
+		}
+	}
+
+	// A condition is evaluated as the filter of a block on the dummy UID 0, so its
+	// variable holds one UID if the condition holds and none otherwise.
+	var upsertQuery strings.Builder
+	upsertQuery.WriteString(strings.TrimSuffix(strings.TrimSpace(q), "}"))
+	condVars := make([]string, len(dms))
+	for i, dm := range dms {
+		if strings.TrimSpace(dm.Cond) == "" {
+			continue
+		}
+		condVars[i] = fmt.Sprintf("__upsert_cond_%d__", i)
+		uidVars[condVars[i]] = nil
+		upsertQuery.WriteString(fmt.Sprintf("\n  %s as var(func: uid(0)) %s", condVars[i],
+			strings.Replace(dm.Cond, "@if", "@filter", 1)))
+	}
+	upsertQuery.WriteString("\n}")
+
+	parsed, err := dql.ParseWithNeedVars(dql.Request{Str: upsertQuery.String(), Variables: vars},
+		slices.Collect(maps.Keys(uidVars)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing upsert query: %w", err)
+	}
+	engine.logger.V(2).Info("Running upsert", "namespaceID", ns.ID(), "startTs", startTs, "query", upsertQuery.String())
+
+	qctx := x.AttachNamespace(ctx, ns.ID())
+	qr := query.Request{
+		ReadTs:   startTs,
+		Latency:  &query.Latency{Start: time.Now()},
+		DqlQuery: &parsed,
+	}
+	er, err := qr.Process(qctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error processing upsert query: %w", err)
+	}
+	resp := &api.Response{}
+	if resp.Json, err = query.ToJson(qctx, qr.Latency, er.Subgraphs, nil); err != nil {
+		return nil, nil, err
+	}
+
+	for name := range uidVars {
+		v := qr.Vars[name]
+		var list []uint64
+		if v.Uids != nil && len(v.Uids.Uids) > 0 {
+			list = v.Uids.Uids
+		} else if v.Vals != nil {
+			_ = v.Vals.Iterate(func(uid uint64, _ types.Val) error {
+				list = append(list, uid)
+				return nil
+			})
+		}
+		for _, uid := range list {
+			uidVars[name] = append(uidVars[name], fmt.Sprintf("%#x", uid))
+		}
+	}
 
-	return newUids, worker.ApplyCommited(ctx, &pb.OracleDelta{
+	applied := dms[:0]
+	for i, dm := range dms {
+		if condVars[i] != "" && len(uidVars[condVars[i]]) != 1 {
+			continue
+		}
+		dm.Set = substituteUIDVars(dm.Set, uidVars, true)
+		dm.Del = substituteUIDVars(dm.Del, uidVars, false)
+		if len(dm.Set) > 0 || len(dm.Del) > 0 {
+			applied = append(applied, dm)
+		}
+	}
+	if len(applied) == 0 {
+		return resp, nil, nil
+	}
+
+	uids, err := engine.mutateDqlWithLock(ctx, ns, startTs, applied)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, uids, nil
+}
+
+// substituteUIDVars replaces uid(v) in the subjects and objects of the nquads by each
+// UID of the variable v. If v holds no UID, it is replaced by the blank node _:uid(v)
+// in set nquads, while delete nquads are dropped.
+func substituteUIDVars(nquads []*api.NQuad, uidVars map[string][]string, isSet bool) []*api.NQuad {
+	resolve := func(id string) []string {
+		if !strings.HasPrefix(id, "uid(") {
+			return []string{id}
+		}
+		if uids := uidVars[id[4:len(id)-1]]; len(uids) > 0 {
+			return uids
+		}
+		if isSet {
+			return []string{"_:" + id}
+		}
+		return nil
+	}
+
+	result := make([]*api.NQuad, 0, len(nquads))
+	for _, nq := range nquads {
+		for _, subject := range resolve(nq.Subject) {
+			for _, object := range resolve(nq.ObjectId) {
+				n := proto.Clone(nq).(*api.NQuad)
+				n.Subject = subject
+				n.ObjectId = object
+				result = append(result, n)
+			}
+		}
+	}
+	return result
+}
+
+// commitTxn commits the transaction started at startTs and returns its commit
+// timestamp. If another transaction committed a conflicting write after startTs,
+// the transaction is aborted instead and dgo.ErrAborted is returned.
+func (engine *Engine) commitTxn(ctx context.Context, startTs uint64) (uint64, error) {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return 0, ErrClosedEngine
+	}
+	if !engine.oracle.isOpen(startTs) {
+		return 0, dgo.ErrAborted
+	}
+	return engine.commitWithLock(ctx, startTs)
+}
+
+func (engine *Engine) commitWithLock(ctx context.Context, startTs uint64) (uint64, error) {
+	txn := posting.Oracle().GetTxn(startTs)
+	if txn == nil {
+		// the pending writes are gone, e.g. because the data was dropped meanwhile
+		engine.oracle.done(startTs)
+		engine.feed.discard(startTs)
+		return 0, dgo.ErrAborted
+	}
+
+	tc := &api.TxnContext{}
+	txn.FillContext(tc, 1, false)
+	if engine.oracle.hasConflict(tc) {
+		engine.logger.V(1).Info("Aborting conflicting transaction", "startTs", startTs)
+		if err := engine.abortWithLock(ctx, startTs); err != nil {
+			return 0, err
+		}
+		return 0, dgo.ErrAborted
+	}
+
+	commitTs, err := engine.z.nextTs()
+	if err != nil {
+		return 0, err
+	}
+	if err := worker.ApplyCommited(ctx, &pb.OracleDelta{
 		Txns: []*pb.TxnStatus{{StartTs: startTs, CommitTs: commitTs}},
+	}); err != nil {
+		if abortErr := engine.abortWithLock(ctx, startTs); abortErr != nil {
+			engine.logger.Error(abortErr, "Failed to abort transaction", "startTs", startTs)
+		}
+		return 0, err
+	}
+	engine.oracle.done(startTs)
+	engine.oracle.commit(tc, commitTs)
+	engine.feed.commit(startTs, commitTs)
+	return commitTs, nil
+}
+
+// abortTxn discards all pending writes of the transaction started at startTs.
+func (engine *Engine) abortTxn(ctx context.Context, startTs uint64) error {
+	engine.mutex.Lock()
+	defer engine.mutex.Unlock()
+
+	if !engine.isOpen.Load() {
+		return ErrClosedEngine
+	}
+	return engine.abortWithLock(ctx, startTs)
+}
+
+func (engine *Engine) abortWithLock(ctx context.Context, startTs uint64) error {
+	engine.oracle.done(startTs)
+	engine.feed.discard(startTs)
+	if posting.Oracle().GetTxn(startTs) == nil {
+		return nil
+	}
+	return worker.ApplyCommited(ctx, &pb.OracleDelta{
+		Txns: []*pb.TxnStatus{{StartTs: startTs}},
 	})
 }
 
@@ -404,7 +1365,14 @@ func (engine *Engine) Close() {
 	}
 
 	engine.isOpen.Store(false)
+	engine.conns.Range(func(client, _ any) bool {
+		engine.closeClient(client.(*dgo.Dgraph))
+		return true
+	})
+	engine.server.Stop()
+	engine.feed.close()
 	x.UpdateHealthStatus(false)
+	posting.ResetCache()
 	posting.Cleanup()
 	worker.State.Dispose()
 
@@ -421,8 +1389,21 @@ func (ns *Engine) reset() error {
 	}
 
 	if !restart {
-		if err := worker.ApplyInitialSchema(0, 1); err != nil {
-			return fmt.Errorf("error applying initial schema: %w", err)
+		// dropping all data keeps the namespaces, and the IDs of deleted ones are never reused
+		if ns.z != nil {
+			z.lastNamespace = ns.z.lastNamespace
+			z.deletedNamespaces = ns.z.deletedNamespaces
+			if err := z.writeZeroState(); err != nil {
+				return fmt.Errorf("error restoring zero state: %w", err)
+			}
+		}
+		for nsID := uint64(0); nsID <= z.lastNamespace; nsID++ {
+			if z.isDeletedNamespace(nsID) {
+				continue
+			}
+			if err := worker.ApplyInitialSchema(nsID, 1); err != nil {
+				return fmt.Errorf("error applying initial schema: %w", err)
+			}
 		}
 	}
 
@@ -434,5 +1415,6 @@ func (ns *Engine) reset() error {
 	}
 
 	ns.z = z
+	ns.oracle = newOracle()
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -82,6 +83,52 @@ func TestSchemaQuery(t *testing.T) {
 		string(resp.GetJson()))
 }
 
+func TestSchemaQueryAllPredicates(t *testing.T) {
+	engine, err := modusgraph.NewEngine(modusgraph.NewDefaultConfig(t.TempDir()))
+	require.NoError(t, err)
+	defer engine.Close()
+
+	require.NoError(t, engine.DropAll(context.Background()))
+	require.NoError(t, engine.GetDefaultNamespace().AlterSchema(context.Background(), `
+		name: string @index(exact) .
+		friends: [uid] @reverse .
+		type Person {
+			name
+			friends
+		}
+	`))
+
+	resp, err := engine.GetDefaultNamespace().Query(context.Background(), `schema {}`)
+	require.NoError(t, err)
+
+	var result struct {
+		Schema []struct {
+			Predicate string   `json:"predicate"`
+			Type      string   `json:"type"`
+			Tokenizer []string `json:"tokenizer"`
+			Reverse   bool     `json:"reverse"`
+			List      bool     `json:"list"`
+		} `json:"schema"`
+		Types []struct {
+			Name string `json:"name"`
+		} `json:"types"`
+	}
+	require.NoError(t, json.Unmarshal(resp.GetJson(), &result))
+	require.NotEmpty(t, result.Types, "Types should still be returned")
+
+	predicates := make(map[string]int)
+	for i, s := range result.Schema {
+		predicates[s.Predicate] = i
+	}
+	require.Contains(t, predicates, "name", "Predicates should be returned without naming them")
+	require.Contains(t, predicates, "friends")
+	require.Equal(t, []string{"exact"}, result.Schema[predicates["name"]].Tokenizer)
+	friends := result.Schema[predicates["friends"]]
+	require.Equal(t, "uid", friends.Type)
+	require.True(t, friends.Reverse)
+	require.True(t, friends.List)
+}
+
 func TestBasicVector(t *testing.T) {
 	vect := []float32{5.1, 5.1, 1.1}
 	buf := new(bytes.Buffer)
@@ -124,3 +171,59 @@ func TestBasicVector(t *testing.T) {
 		`{"q":[{"project_description_v":[5.1E+00,5.1E+00,1.1E+00]}]}`,
 		string(resp.GetJson()))
 }
+
+func TestUniqueConstraint(t *testing.T) {
+	engine, err := modusgraph.NewEngine(modusgraph.NewDefaultConfig(t.TempDir()))
+	require.NoError(t, err)
+	defer engine.Close()
+
+	ctx := context.Background()
+	ns := engine.GetDefaultNamespace()
+	require.NoError(t, engine.DropAll(ctx))
+	require.NoError(t, ns.AlterSchema(ctx, `
+		email: string @index(exact) @unique .
+		name: string .
+	`))
+
+	setEmail := func(subject, email string) *api.NQuad {
+		return &api.NQuad{
+			Subject:     subject,
+			Predicate:   "email",
+			ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: email}},
+		}
+	}
+
+	uids, err := ns.Mutate(ctx, []*api.Mutation{{
+		Set: []*api.NQuad{setEmail("_:alice", "alice@example.com"), setEmail("_:bob", "bob@example.com")},
+	}})
+	require.NoError(t, err)
+	alice := fmt.Sprintf("%#x", uids["_:alice"])
+	bob := fmt.Sprintf("%#x", uids["_:bob"])
+
+	_, err = ns.Mutate(ctx, []*api.Mutation{{Set: []*api.NQuad{setEmail("_:eve", "alice@example.com")}}})
+	var uniqueErr *modusgraph.UniqueError
+	require.ErrorAs(t, err, &uniqueErr, "A value held by another node should be rejected")
+	require.Equal(t, "email", uniqueErr.Field)
+	require.Equal(t, "alice@example.com", uniqueErr.Value)
+	require.Equal(t, alice, uniqueErr.UID)
+
+	_, err = ns.Mutate(ctx, []*api.Mutation{{
+		Set: []*api.NQuad{setEmail("_:eve", "eve@example.com"), setEmail("_:mallory", "eve@example.com")},
+	}})
+	require.ErrorAs(t, err, &uniqueErr, "Duplicate values within a mutation should be rejected")
+
+	_, err = ns.Mutate(ctx, []*api.Mutation{{
+		Set: []*api.NQuad{setEmail(alice, "alice@example.com")},
+	}})
+	require.NoError(t, err, "A node should be able to set its own value again")
+
+	_, err = ns.Mutate(ctx, []*api.Mutation{{
+		Set: []*api.NQuad{setEmail(alice, "bob@example.com"), setEmail(bob, "alice@example.com")},
+	}})
+	require.NoError(t, err, "Nodes should be able to swap their values")
+
+	resp, err := ns.Query(ctx, `{ q(func: has(email), orderasc: email) { uid email } }`)
+	require.NoError(t, err)
+	require.JSONEq(t, fmt.Sprintf(`{"q":[{"uid":%q,"email":"alice@example.com"},{"uid":%q,"email":"bob@example.com"}]}`,
+		bob, alice), string(resp.GetJson()))
+}
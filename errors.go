@@ -5,8 +5,173 @@
 
 package modusgraph
 
-import dg "github.com/dolan-in/dgman/v2"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	dg "github.com/dolan-in/dgman/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 // UniqueError represents an error that occurs when attempting to insert or update
-// a node that would violate a unique constraint.
+// a node that would violate a unique constraint. Field and Value are the predicate
+// and the duplicate value, and UID and NodeType identify the node that already has it.
 type UniqueError = dg.UniqueError
+
+// uniqueErrorReason is the reason of the gRPC error details that carry a UniqueError
+// from the embedded engine to the client
+const uniqueErrorReason = "UNIQUE_CONSTRAINT"
+
+// dgraphUniqueError matches the error Dgraph returns for a duplicate value of a
+// @unique predicate
+var dgraphUniqueError = regexp.MustCompile(`could not insert duplicate value \[(.*)\] for predicate \[(.*)\]`)
+
+// uniqueErrorStatus returns err as a gRPC error whose details hold its fields.
+func uniqueErrorStatus(err *UniqueError) error {
+	st, detailsErr := status.New(codes.AlreadyExists, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason: uniqueErrorReason,
+		Metadata: map[string]string{
+			"nodeType": err.NodeType,
+			"field":    err.Field,
+			"value":    fmt.Sprintf("%v", err.Value),
+			"uid":      err.UID,
+		},
+	})
+	if detailsErr != nil {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return st.Err()
+}
+
+// asUniqueError returns err as a *UniqueError if it reports a violated unique
+// constraint, either from the embedded engine or from Dgraph, and err otherwise.
+func asUniqueError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var uniqueErr *UniqueError
+	if errors.As(err, &uniqueErr) {
+		return err
+	}
+	if st, ok := status.FromError(err); ok {
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == uniqueErrorReason {
+				return &UniqueError{
+					NodeType: info.Metadata["nodeType"],
+					Field:    info.Metadata["field"],
+					Value:    info.Metadata["value"],
+					UID:      info.Metadata["uid"],
+				}
+			}
+		}
+	}
+	if m := dgraphUniqueError.FindStringSubmatch(err.Error()); m != nil {
+		return &UniqueError{Field: m[2], Value: m[1]}
+	}
+	return err
+}
+
+// ErrNodeNotFound is returned by Get when there is no node with the UID. The
+// *NotFoundError returned by GetBy matches it with errors.Is.
+var ErrNodeNotFound = dg.ErrNodeNotFound
+
+// NotFoundError is returned by GetBy when no node of the type has the value.
+type NotFoundError struct {
+	NodeType  string
+	Predicate string
+	Value     any
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s with %s %v not found", e.NodeType, e.Predicate, e.Value)
+}
+
+// Is reports whether target is ErrNodeNotFound.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNodeNotFound
+}
+
+// ErrStaleVersion is matched with errors.Is by the *StaleVersionError returned when an
+// object is written with a version that is no longer the stored one.
+var ErrStaleVersion = errors.New("stale version")
+
+// StaleVersionError is returned by Update, UpdateFields and Upsert when the version field
+// of an object, tagged `dgraph:"version"`, differs from the version stored for its node,
+// which means that the node was written since the object was read. Nothing is written.
+type StaleVersionError struct {
+	NodeType string
+	UID      string
+	// Version is the version of the object
+	Version int64
+	// Stored is the version stored for the node
+	Stored int64
+}
+
+func (e *StaleVersionError) Error() string {
+	return fmt.Sprintf("%s %s is at version %d, not %d", e.NodeType, e.UID, e.Stored, e.Version)
+}
+
+// Is reports whether target is ErrStaleVersion.
+func (e *StaleVersionError) Is(target error) bool {
+	return target == ErrStaleVersion
+}
+
+// BatchError is returned by InsertBatch when some of the objects could not be inserted.
+// A batch is inserted atomically, so every object of a failed batch is reported.
+type BatchError struct {
+	// Failures maps the index of each object that was not inserted to the error of its batch
+	Failures map[int]error
+	// Total is the number of objects passed to InsertBatch
+	Total int
+
+	// errs holds the error of each failed batch
+	errs []error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("failed to insert %d of %d objects: %v", len(e.Failures), e.Total, e.errs[0])
+}
+
+// Unwrap returns the errors of the failed batches.
+func (e *BatchError) Unwrap() []error {
+	return e.errs
+}
+
+// ValidationError is returned by Insert, Upsert, Update and UpdateFields when objects
+// break the rules of their `validate` tags, in which case none of them is written. InsertBatch
+// returns it within a *BatchError for each batch holding an invalid object.
+type ValidationError struct {
+	// Fields lists every field that breaks a rule
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		msgs[i] = field.Error()
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// FieldError is a field of an object that breaks a validation rule.
+type FieldError struct {
+	// Index is the index of the object in the slice passed, or -1 for a single object
+	Index int
+	// Field is the path of the struct field from the object, e.g. "Name" or "Tasks[2].Title"
+	Field string
+	// Rule is the rule as written in the tag, e.g. "required" or "maxlen=100"
+	Rule string
+	// Value is the value of the field
+	Value any
+}
+
+func (e FieldError) Error() string {
+	if e.Index >= 0 {
+		return fmt.Sprintf("[%d].%s: breaks %s", e.Index, e.Field, e.Rule)
+	}
+	return fmt.Sprintf("%s: breaks %s", e.Field, e.Rule)
+}
@@ -0,0 +1,194 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// QueryOpt is a function that configures a query performed by Find, Iterate, SimilarTo or
+// HybridSearch.
+type QueryOpt func(*queryOptions)
+
+// queryOptions holds the options of a query performed by Find, Iterate, SimilarTo or
+// HybridSearch.
+//
+// filter: a DQL filter expression applied to the nodes.
+// vars: the values of the variables referenced in the filter.
+// orders: the predicates to order the results by.
+// first: the maximum number of results, zero for no limit.
+// offset: the number of results to skip.
+// depth: the number of levels of edges to fetch, negative for the client default.
+// metric: the distance metric used by SimilarTo and HybridSearch.
+// boosts: the text matches that raise the score of results of HybridSearch.
+// pageSize: the number of objects Iterate fetches per query.
+type queryOptions struct {
+	filter   string
+	vars     map[string]string
+	orders   []queryOrder
+	first    int
+	offset   int
+	depth    int
+	metric   string
+	boosts   []textBoost
+	pageSize int
+}
+
+type queryOrder struct {
+	predicate string
+	desc      bool
+}
+
+// WithFilter filters the results with a DQL filter expression, for example
+// `eq(name, $name) AND gt(age, 30)`. Values for variables referenced in the filter
+// are passed in vars, keyed by the variable name including the `$`.
+func WithFilter(filter string, vars map[string]string) QueryOpt {
+	return func(o *queryOptions) {
+		o.filter = filter
+		o.vars = vars
+	}
+}
+
+// WithOrderAsc orders the results by the predicate in ascending order. Repeat to
+// order by several predicates.
+func WithOrderAsc(predicate string) QueryOpt {
+	return func(o *queryOptions) {
+		o.orders = append(o.orders, queryOrder{predicate: predicate})
+	}
+}
+
+// WithOrderDesc orders the results by the predicate in descending order. Repeat to
+// order by several predicates.
+func WithOrderDesc(predicate string) QueryOpt {
+	return func(o *queryOptions) {
+		o.orders = append(o.orders, queryOrder{predicate: predicate, desc: true})
+	}
+}
+
+// WithFirst limits the number of results to n.
+func WithFirst(n int) QueryOpt {
+	return func(o *queryOptions) {
+		o.first = n
+	}
+}
+
+// WithOffset skips the first n results.
+func WithOffset(n int) QueryOpt {
+	return func(o *queryOptions) {
+		o.offset = n
+	}
+}
+
+// WithDepth sets how many levels of edges are fetched for each result. It defaults
+// to the maximum edge traversal of the client.
+func WithDepth(depth int) QueryOpt {
+	return func(o *queryOptions) {
+		o.depth = depth
+	}
+}
+
+// WithMetric sets the distance metric SimilarTo and HybridSearch use for each result, one of
+// "euclidean", "cosine" or "dotproduct". It should match the metric of the HNSW
+// index on the predicate, and defaults to "euclidean" as Dgraph does.
+func WithMetric(metric string) QueryOpt {
+	return func(o *queryOptions) {
+		o.metric = metric
+	}
+}
+
+// Find retrieves all objects of type T that match the options. T must be a struct
+// type defined in the same way as objects passed to Insert, and the type of the
+// nodes to return is derived from it.
+//
+// Example:
+//
+//	users, err := modusgraph.Find[User](ctx, client,
+//		modusgraph.WithFilter("eq(name, $name)", map[string]string{"$name": "Alice"}),
+//		modusgraph.WithOrderAsc("createdAt"),
+//		modusgraph.WithFirst(10))
+func Find[T any](ctx context.Context, c Client, opts ...QueryOpt) ([]T, error) {
+	var result []T
+	err := intercept(ctx, c, "Find", new(T), func(ctx context.Context) error {
+		var err error
+		result, err = find[T](ctx, c, opts)
+		return err
+	})
+	return result, err
+}
+
+func find[T any](ctx context.Context, c Client, opts []QueryOpt) ([]T, error) {
+	options := newQueryOptions(c, opts)
+
+	dgoClient, cleanup, err := c.DgraphClient()
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	var model T
+	q := dg.NewReadOnlyTxnContext(ctx, dgoClient).Get(&model).All(options.depth)
+	if options.filter != "" {
+		q.Filter(options.filter)
+	}
+	if len(options.vars) > 0 {
+		q.Vars(varsDefinition("find", options.vars), options.vars)
+	}
+	for _, order := range options.orders {
+		if order.desc {
+			q.OrderDesc(order.predicate)
+		} else {
+			q.OrderAsc(order.predicate)
+		}
+	}
+	if options.first > 0 {
+		q.First(options.first)
+	}
+	if options.offset > 0 {
+		q.Offset(options.offset)
+	}
+
+	result := []T{}
+	if err := q.Nodes(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// newQueryOptions applies opts over the defaults for queries made with c.
+func newQueryOptions(c Client, opts []QueryOpt) queryOptions {
+	options := queryOptions{depth: -1, metric: metricEuclidean}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	cl, ok := c.(client)
+	if options.depth < 0 {
+		options.depth = defaultMaxEdgeTraversal
+		if ok {
+			options.depth = cl.options.maxEdgeTraversal
+		}
+	}
+	if ok {
+		options.filter = cl.liveFilter(options.filter)
+	}
+	return options
+}
+
+// varsDefinition returns the definition of a DQL query block taking the variables,
+// for example `find($name: string)`. Variables are declared as strings, which Dgraph
+// converts to the type of the predicate they are compared with. Declarations of
+// variables with other types are passed in typed, for example `$vec: float32vector`.
+func varsDefinition(name string, vars map[string]string, typed ...string) string {
+	defs := slices.Clone(typed)
+	for _, v := range slices.Sorted(maps.Keys(vars)) {
+		defs = append(defs, v+": string")
+	}
+	return name + "(" + strings.Join(defs, ", ") + ")"
+}
@@ -0,0 +1,82 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "FindWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "FindWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			entities := []*TestEntity{
+				{Name: "Charlie", Description: "Third entity", CreatedAt: time.Now()},
+				{Name: "Alice", Description: "First entity", CreatedAt: time.Now()},
+				{Name: "Bob", Description: "Second entity", CreatedAt: time.Now()},
+			}
+			require.NoError(t, client.Insert(ctx, entities), "Insert should succeed")
+
+			all, err := modusgraph.Find[TestEntity](ctx, client, modusgraph.WithOrderAsc("name"))
+			require.NoError(t, err, "Find should succeed")
+			require.Len(t, all, 3, "Find should return all entities")
+			require.Equal(t, "Alice", all[0].Name)
+			require.Equal(t, "Bob", all[1].Name)
+			require.Equal(t, "Charlie", all[2].Name)
+			require.NotEmpty(t, all[0].UID, "UID should be returned")
+
+			filtered, err := modusgraph.Find[TestEntity](ctx, client,
+				modusgraph.WithFilter("eq(name, $name)", map[string]string{"$name": "Bob"}))
+			require.NoError(t, err, "Find with a filter should succeed")
+			require.Len(t, filtered, 1, "Find should return the matching entity")
+			require.Equal(t, "Second entity", filtered[0].Description)
+
+			paged, err := modusgraph.Find[TestEntity](ctx, client,
+				modusgraph.WithOrderDesc("name"),
+				modusgraph.WithFirst(1),
+				modusgraph.WithOffset(1))
+			require.NoError(t, err, "Find with pagination should succeed")
+			require.Len(t, paged, 1, "Find should return one page")
+			require.Equal(t, "Bob", paged[0].Name)
+
+			none, err := modusgraph.Find[TestEntity](ctx, client,
+				modusgraph.WithFilter("eq(name, $name)", map[string]string{"$name": "Nobody"}))
+			require.NoError(t, err, "Find without matches should succeed")
+			require.Empty(t, none, "Find should return no entities")
+		})
+	}
+}
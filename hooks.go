@@ -0,0 +1,161 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// BeforeInserter is implemented by objects that run code before they are inserted or
+// upserted, e.g. to normalize their fields. An error cancels the operation.
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInserter is implemented by objects that run code once they are inserted or upserted
+// and have a UID. Within a transaction, it runs before the transaction commits.
+type AfterInserter interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// BeforeUpdater is implemented by objects that run code before they are updated with
+// Update or UpdateFields. An error cancels the operation.
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// BeforeDeleter is implemented by objects that run code before they are deleted. Delete
+// only has the UIDs of the objects, so it calls the hook when the type of the objects is
+// given with WithModel, on each object read into a new value of that type. An error
+// cancels the operation.
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// Interceptor wraps the operations of a client and of its transactions. It is called with
+// the name of the operation, e.g. "Insert" or "Commit", and its main argument, such as the
+// object inserted, the UIDs deleted or the query run, and must call next to run the
+// operation, returning its error. Interceptors added first are the outermost.
+//
+// Example:
+//
+//	func metrics(ctx context.Context, op string, obj any, next func(context.Context) error) error {
+//		start := time.Now()
+//		err := next(ctx)
+//		record(op, time.Since(start), err)
+//		return err
+//	}
+type Interceptor func(ctx context.Context, op string, obj any, next func(context.Context) error) error
+
+// WithInterceptor adds an interceptor that wraps every operation of the client, for
+// cross-cutting concerns such as audit logs or metrics. Clients for the same URI and other
+// options share their connections, each with its own interceptors.
+func WithInterceptor(interceptor Interceptor) ClientOpt {
+	return func(o *clientOptions) {
+		o.interceptors = append(o.interceptors, interceptor)
+	}
+}
+
+// intercept runs fn as the operation op on obj through the interceptors of the client.
+func (c client) intercept(ctx context.Context, op string, obj any, fn func(context.Context) error) error {
+	next := fn
+	for i := len(c.options.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.options.interceptors[i], next
+		next = func(ctx context.Context) error {
+			return interceptor(ctx, op, obj, inner)
+		}
+	}
+	return next(ctx)
+}
+
+// intercept runs fn as the operation op on obj through the interceptors of c, for the
+// operations that are functions taking a Client.
+func intercept(ctx context.Context, c Client, op string, obj any, fn func(context.Context) error) error {
+	if cl, ok := c.(client); ok {
+		return cl.intercept(ctx, op, obj, fn)
+	}
+	return fn(ctx)
+}
+
+// queryRaw runs the DQL query q on c without going through its interceptors, for the
+// operations that are intercepted as a whole.
+func queryRaw(ctx context.Context, c Client, q string, vars map[string]string) ([]byte, error) {
+	if cl, ok := c.(client); ok {
+		return cl.queryRaw(ctx, nil, q, vars)
+	}
+	return c.QueryRaw(ctx, q, vars)
+}
+
+// callHooks calls hook with obj, or with each element of obj if it is a slice or a pointer
+// to a slice, if it implements the hook interface H. It stops at the first error.
+func callHooks[H any](obj any, hook func(H) error) error {
+	val := reflect.ValueOf(obj)
+	if val.Kind() == reflect.Ptr && val.Elem().Kind() == reflect.Slice {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice {
+		if h, ok := obj.(H); ok {
+			return hook(h)
+		}
+		return nil
+	}
+	for i := range val.Len() {
+		if h, ok := val.Index(i).Interface().(H); ok {
+			if err := hook(h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// beforeHooks calls the hooks of obj that run before the operation.
+func beforeHooks(ctx context.Context, operation string, obj any) error {
+	switch operation {
+	case "Insert", "Upsert":
+		return callHooks(obj, func(h BeforeInserter) error { return h.BeforeInsert(ctx) })
+	case "Update":
+		return callHooks(obj, func(h BeforeUpdater) error { return h.BeforeUpdate(ctx) })
+	}
+	return nil
+}
+
+// afterHooks calls the hooks of obj that run once the operation succeeded.
+func afterHooks(ctx context.Context, operation string, obj any) error {
+	switch operation {
+	case "Insert", "Upsert":
+		return callHooks(obj, func(h AfterInserter) error { return h.AfterInsert(ctx) })
+	}
+	return nil
+}
+
+// beforeDeleteHooks reads each of the nodes with the given UIDs into a new value of the
+// type of model and calls its BeforeDelete hook, if the type implements BeforeDeleter.
+func beforeDeleteHooks(ctx context.Context, model any, uids []string,
+	get func(obj any, uid string) error) error {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errors.New("model must be a struct")
+	}
+	if !reflect.PointerTo(t).Implements(reflect.TypeFor[BeforeDeleter]()) {
+		return nil
+	}
+	for _, uid := range uids {
+		obj := reflect.New(t).Interface()
+		if err := get(obj, uid); err != nil {
+			return err
+		}
+		if err := obj.(BeforeDeleter).BeforeDelete(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+var errProtected = errors.New("protected items can't be deleted")
+
+type HookItem struct {
+	Name      string `json:"name,omitempty" dgraph:"index=exact upsert"`
+	Revisions int    `json:"revisions,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+
+	insertedUID string
+}
+
+func (h *HookItem) BeforeInsert(ctx context.Context) error {
+	if h.Name == "" {
+		return errors.New("name is required")
+	}
+	h.Name = strings.ToLower(h.Name)
+	return nil
+}
+
+func (h *HookItem) AfterInsert(ctx context.Context) error {
+	h.insertedUID = h.UID
+	return nil
+}
+
+func (h *HookItem) BeforeUpdate(ctx context.Context) error {
+	h.Revisions++
+	return nil
+}
+
+func (h *HookItem) BeforeDelete(ctx context.Context) error {
+	if h.Name == "protected" {
+		return errProtected
+	}
+	return nil
+}
+
+// opRecorder is an interceptor recording the operations it wraps, prefixed by its name.
+type opRecorder struct {
+	mutex sync.Mutex
+	ops   []string
+}
+
+func (r *opRecorder) interceptor(name string) modusgraph.Interceptor {
+	return func(ctx context.Context, op string, obj any, next func(context.Context) error) error {
+		r.mutex.Lock()
+		r.ops = append(r.ops, name+" "+op)
+		r.mutex.Unlock()
+		err := next(ctx)
+		r.mutex.Lock()
+		r.ops = append(r.ops, fmt.Sprintf("%s %s done: %v", name, op, err))
+		r.mutex.Unlock()
+		return err
+	}
+}
+
+func (r *opRecorder) take() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ops := r.ops
+	r.ops = nil
+	return ops
+}
+
+func TestClientHooks(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "HooksWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "HooksWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+			ctx := context.Background()
+
+			item := HookItem{Name: "First"}
+			require.NoError(t, client.Insert(ctx, &item))
+			require.Equal(t, "first", item.Name, "BeforeInsert should run before the insert")
+			require.NotEmpty(t, item.UID)
+			require.Equal(t, item.UID, item.insertedUID, "AfterInsert should run with the assigned UID")
+
+			items := []*HookItem{{Name: "Second"}, {}}
+			require.Error(t, client.Insert(ctx, &items), "An error of BeforeInsert should cancel the insert")
+			require.Empty(t, items[0].UID)
+
+			upserted := HookItem{Name: "FIRST"}
+			require.NoError(t, client.Upsert(ctx, &upserted))
+			require.Equal(t, item.UID, upserted.UID, "BeforeInsert should run before the upsert lookup")
+			require.Equal(t, item.UID, upserted.insertedUID)
+
+			require.NoError(t, client.Update(ctx, &item))
+			require.NoError(t, client.UpdateFields(ctx, &item, "revisions"))
+			var fetched HookItem
+			require.NoError(t, client.Get(ctx, &fetched, item.UID))
+			require.Equal(t, 2, fetched.Revisions, "BeforeUpdate should run before each update")
+
+			protected := HookItem{Name: "protected"}
+			require.NoError(t, client.Insert(ctx, &protected))
+			err := client.Delete(ctx, []string{item.UID, protected.UID}, modusgraph.WithModel(HookItem{}))
+			require.ErrorIs(t, err, errProtected)
+			require.NoError(t, client.Get(ctx, &fetched, item.UID), "An error of BeforeDelete should cancel the delete")
+
+			require.NoError(t, client.Delete(ctx, []string{item.UID, "0xfffffff"}, modusgraph.WithModel(HookItem{})))
+			require.ErrorIs(t, client.Get(ctx, &fetched, item.UID), modusgraph.ErrNodeNotFound)
+			require.NoError(t, client.Delete(ctx, []string{protected.UID}),
+				"BeforeDelete should only run when the model is given")
+		})
+	}
+}
+
+func TestClientInterceptors(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "InterceptorsWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "InterceptorsWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			var recorder opRecorder
+			client, cleanup := CreateTestClient(t, tc.uri,
+				modusgraph.WithInterceptor(recorder.interceptor("outer")),
+				modusgraph.WithInterceptor(recorder.interceptor("inner")))
+			defer cleanup()
+			ctx := context.Background()
+
+			item := HookItem{Name: "first"}
+			require.NoError(t, client.Insert(ctx, &item))
+			require.Equal(t, []string{
+				"outer Insert",
+				"inner Insert",
+				"inner Insert done: <nil>",
+				"outer Insert done: <nil>",
+			}, recorder.take(), "Interceptors should wrap the operation, the first one outermost")
+
+			_, err := modusgraph.Find[HookItem](ctx, client)
+			require.NoError(t, err)
+			_, err = modusgraph.Count[HookItem](ctx, client)
+			require.NoError(t, err)
+			err = client.Get(ctx, &HookItem{}, "0xfffffff")
+			require.ErrorIs(t, err, modusgraph.ErrNodeNotFound)
+			require.Equal(t, []string{
+				"outer Find", "inner Find", "inner Find done: <nil>", "outer Find done: <nil>",
+				"outer Count", "inner Count", "inner Count done: <nil>", "outer Count done: <nil>",
+				"outer Get", "inner Get",
+				fmt.Sprintf("inner Get done: %v", err), fmt.Sprintf("outer Get done: %v", err),
+			}, recorder.take(), "Interceptors should see the errors of the operations")
+
+			// started returns the operations the outer interceptor was called for
+			started := func() []string {
+				var ops []string
+				for _, op := range recorder.take() {
+					if strings.HasPrefix(op, "outer") && !strings.Contains(op, "done") {
+						ops = append(ops, op)
+					}
+				}
+				return ops
+			}
+
+			for _, err := range modusgraph.Iterate[HookItem](ctx, client) {
+				require.NoError(t, err)
+			}
+			require.NoError(t, modusgraph.Scan(ctx, client, func(HookItem) error { return nil }))
+			_, err = modusgraph.Max[HookItem](ctx, client, "revisions")
+			require.NoError(t, err)
+			_, err = modusgraph.GroupBy[HookItem](ctx, client, "name")
+			require.NoError(t, err)
+			watchCtx, cancel := context.WithCancel(ctx)
+			results, err := client.Watch(watchCtx, `{ items(func: type(HookItem)) { name } }`, nil)
+			require.NoError(t, err)
+			<-results
+			_, err = client.Subscribe(watchCtx, modusgraph.ChangeFilter{})
+			if strings.HasPrefix(tc.uri, "dgraph://") {
+				require.ErrorIs(t, err, modusgraph.ErrRemoteChangeFeed)
+			} else {
+				require.NoError(t, err)
+			}
+			cancel()
+			require.Equal(t, []string{
+				"outer Iterate", "outer Scan", "outer Max", "outer GroupBy", "outer Watch", "outer Subscribe",
+			}, started(), "Iterations, aggregations and feeds should be intercepted as themselves")
+
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err)
+			require.NoError(t, tx.Delete(ctx, []string{item.UID}))
+			require.NoError(t, tx.Commit(ctx))
+			require.NoError(t, tx.Discard(ctx))
+			require.Equal(t, []string{"outer Begin", "outer Delete", "outer Commit"}, started(),
+				"Transactions should be intercepted, except for discarding after the commit")
+		})
+	}
+}
+
+func TestLocalClientInterceptorsSharedEngine(t *testing.T) {
+	path := GetTempDir(t)
+	ctx := context.Background()
+
+	var first, second opRecorder
+	firstClient, cleanup := CreateTestClient(t, "file://"+path,
+		modusgraph.WithInterceptor(first.interceptor("first")))
+	defer cleanup()
+	secondClient, err := modusgraph.NewClient("file://"+path, modusgraph.WithAutoSchema(true),
+		modusgraph.WithInterceptor(second.interceptor("second")))
+	require.NoError(t, err, "Clients with interceptors should share the engine of the path")
+	plainClient, err := modusgraph.NewClient("file://"+path, modusgraph.WithAutoSchema(true))
+	require.NoError(t, err)
+
+	require.NoError(t, firstClient.Insert(ctx, &HookItem{Name: "first"}))
+	require.NoError(t, secondClient.Insert(ctx, &HookItem{Name: "second"}))
+	require.NoError(t, plainClient.Insert(ctx, &HookItem{Name: "plain"}))
+	require.Equal(t, []string{"first Insert", "first Insert done: <nil>"}, first.take(),
+		"Each client should only run its own interceptors")
+	require.Equal(t, []string{"second Insert", "second Insert done: <nil>"}, second.take())
+
+	secondClient.Close()
+	secondClient.Close()
+	plainClient.Close()
+	items, err := modusgraph.Find[HookItem](ctx, firstClient)
+	require.NoError(t, err, "Closing the other clients should leave the shared connections open")
+	require.Len(t, items, 3)
+}
@@ -0,0 +1,168 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+)
+
+// textBoost raises the score of HybridSearch results whose predicate matches text.
+//
+// function: the DQL text function used to match, anyofterms or anyoftext.
+// predicate: the predicate to match, which needs a term or fulltext index.
+// text: the terms or text to match.
+// weight: the amount added to the score of matching results.
+type textBoost struct {
+	function  string
+	predicate string
+	text      string
+	weight    float64
+}
+
+// name returns the name of the query block and variable of the i-th boost.
+func (b textBoost) name(i int) string {
+	return "boost" + strconv.Itoa(i)
+}
+
+// WithTermBoost adds weight to the score of HybridSearch results whose predicate
+// contains any of the terms. The predicate must have a term index.
+func WithTermBoost(predicate, terms string, weight float64) QueryOpt {
+	return func(o *queryOptions) {
+		o.boosts = append(o.boosts, textBoost{
+			function:  "anyofterms",
+			predicate: predicate,
+			text:      terms,
+			weight:    weight,
+		})
+	}
+}
+
+// WithFullTextBoost adds weight to the score of HybridSearch results whose predicate
+// matches the text after stemming and stop word removal. The predicate must have a
+// fulltext index.
+func WithFullTextBoost(predicate, text string, weight float64) QueryOpt {
+	return func(o *queryOptions) {
+		o.boosts = append(o.boosts, textBoost{
+			function:  "anyoftext",
+			predicate: predicate,
+			text:      text,
+			weight:    weight,
+		})
+	}
+}
+
+// HybridSearch implements retrieval combining vector similarity with text matches.
+// The k nearest nodes are retrieved along with the nodes among them matching each
+// boost in a single query, then ranked by score. As results are always ordered by
+// score, the WithOrderAsc and WithOrderDesc options are rejected.
+func (c client) HybridSearch(ctx context.Context, results any, predicate string, vec []float32, k int,
+	opts ...QueryOpt) ([]float64, error) {
+
+	var scores []float64
+	err := c.intercept(ctx, "HybridSearch", results, func(ctx context.Context) error {
+		var err error
+		scores, err = c.hybridSearch(ctx, results, predicate, vec, k, opts)
+		return err
+	})
+	return scores, err
+}
+
+// hybridSearch runs HybridSearch, decoding the ranked nodes into results.
+func (c client) hybridSearch(ctx context.Context, results any, predicate string, vec []float32, k int,
+	opts []QueryOpt) ([]float64, error) {
+
+	options := newQueryOptions(c, opts)
+	if len(options.orders) > 0 {
+		return nil, errors.New("hybrid search does not support ordering, results are ordered by score")
+	}
+	resp, err := c.similarTo(ctx, predicate, vec, k, options, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []stdjson.RawMessage
+	if err := resultJSON.Unmarshal(resp[similarBlock], &hits); err != nil {
+		return nil, err
+	}
+	type scoredHit struct {
+		raw   stdjson.RawMessage
+		score float64
+	}
+	scored := make([]scoredHit, len(hits))
+	index := make(map[string]int, len(hits))
+	for i, hit := range hits {
+		var h struct {
+			UID      string  `json:"uid"`
+			Distance float64 `json:"_distance"`
+		}
+		if err := resultJSON.Unmarshal(hit, &h); err != nil {
+			return nil, err
+		}
+		scored[i] = scoredHit{raw: hit, score: similarity(options.metric, h.Distance)}
+		index[h.UID] = i
+	}
+	for i, boost := range options.boosts {
+		var matches []struct {
+			UID string `json:"uid"`
+		}
+		if data, ok := resp[boost.name(i)]; ok {
+			if err := resultJSON.Unmarshal(data, &matches); err != nil {
+				return nil, err
+			}
+		}
+		for _, match := range matches {
+			if j, ok := index[match.UID]; ok {
+				scored[j].score += boost.weight
+			}
+		}
+	}
+
+	// hits are ordered by distance, a stable sort keeps that order between equal scores
+	slices.SortStableFunc(scored, func(a, b scoredHit) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		}
+		return 0
+	})
+	scored = scored[min(options.offset, len(scored)):]
+	if options.first > 0 && options.first < len(scored) {
+		scored = scored[:options.first]
+	}
+
+	raws := make([][]byte, len(scored))
+	scores := make([]float64, len(scored))
+	for i, hit := range scored {
+		raws[i] = hit.raw
+		scores[i] = hit.score
+	}
+	ranked := append(append([]byte("["), bytes.Join(raws, []byte(","))...), ']')
+	if err := resultJSON.Unmarshal(ranked, results); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// similarity converts a distance under metric to a similarity score, where larger
+// values are more similar: one minus the distance for cosine, the inverse of one
+// plus the distance for euclidean, and the dot product itself for dotproduct.
+func similarity(metric string, distance float64) float64 {
+	switch metric {
+	case metricCosine:
+		return 1 - distance
+	case metricEuclidean:
+		return 1 / (1 + distance)
+	default:
+		return distance
+	}
+}
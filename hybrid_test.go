@@ -0,0 +1,124 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+type HybridTestOrg struct {
+	Name string `json:"orgName,omitempty" dgraph:"index=exact"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type HybridTestAuthor struct {
+	Name string         `json:"authorName,omitempty" dgraph:"index=exact"`
+	Org  *HybridTestOrg `json:"org,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type HybridTestDoc struct {
+	Title     string            `json:"title,omitempty" dgraph:"index=term"`
+	Body      string            `json:"body,omitempty" dgraph:"index=fulltext"`
+	Embedding []float32         `json:"docEmbedding,omitempty" dgraph:"vector,index=hnsw,metric=cosine"`
+	Author    *HybridTestAuthor `json:"author,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientHybridSearch(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "HybridSearchWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "HybridSearchWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			org := &HybridTestOrg{Name: "Hypermode"}
+			author := &HybridTestAuthor{Name: "Ada", Org: org}
+			docs := []*HybridTestDoc{
+				{Title: "graph databases", Body: "Storing connected data", Embedding: []float32{1, 0}, Author: author},
+				{Title: "vector search", Body: "Searching embeddings of documents", Embedding: []float32{0.8, 0.6}},
+				{Title: "cooking", Body: "Recipes for pasta", Embedding: []float32{0, 1}},
+			}
+			require.NoError(t, client.Insert(ctx, docs), "Insert should succeed")
+
+			query := []float32{1, 0.1}
+			var results []HybridTestDoc
+			scores, err := client.HybridSearch(ctx, &results, "docEmbedding", query, 3,
+				modusgraph.WithMetric("cosine"))
+			require.NoError(t, err, "HybridSearch without boosts should succeed")
+			require.Len(t, results, 3)
+			require.Equal(t, "graph databases", results[0].Title, "Results should be ranked by similarity")
+			require.Greater(t, scores[0], scores[1])
+			require.Greater(t, scores[1], scores[2])
+
+			results = nil
+			scores, err = client.HybridSearch(ctx, &results, "docEmbedding", query, 3,
+				modusgraph.WithMetric("cosine"),
+				modusgraph.WithFullTextBoost("body", "searched embedding", 0.5),
+				modusgraph.WithTermBoost("title", "vector", 0.25),
+				modusgraph.WithFirst(2))
+			require.NoError(t, err, "HybridSearch with boosts should succeed")
+			require.Len(t, results, 2, "WithFirst should limit the ranked results")
+			require.Equal(t, "vector search", results[0].Title, "Text matches should boost the score")
+			require.Equal(t, "graph databases", results[1].Title)
+			require.Greater(t, scores[0], 1.0, "Both boosts should be added to the score")
+
+			results = nil
+			_, err = client.HybridSearch(ctx, &results, "docEmbedding", query, 1,
+				modusgraph.WithMetric("cosine"), modusgraph.WithDepth(2))
+			require.NoError(t, err, "HybridSearch with edge expansion should succeed")
+			require.Len(t, results, 1)
+			require.NotNil(t, results[0].Author, "The first hop should be expanded")
+			require.Equal(t, "Ada", results[0].Author.Name)
+			require.NotNil(t, results[0].Author.Org, "The second hop should be expanded")
+			require.Equal(t, "Hypermode", results[0].Author.Org.Name)
+
+			results = nil
+			_, err = client.HybridSearch(ctx, &results, "docEmbedding", query, 1,
+				modusgraph.WithMetric("cosine"), modusgraph.WithDepth(1))
+			require.NoError(t, err)
+			require.NotNil(t, results[0].Author)
+			require.Nil(t, results[0].Author.Org, "Edges beyond the depth should not be expanded")
+
+			_, err = client.HybridSearch(ctx, &results, "docEmbedding", query, 3,
+				modusgraph.WithOrderAsc("title"))
+			require.Error(t, err, "HybridSearch should reject ordering, results are ordered by score")
+		})
+	}
+}
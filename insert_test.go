@@ -9,6 +9,7 @@ import (
 	"context"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -101,6 +102,43 @@ type OuterTestEntity struct {
 	DType []string `json:"dgraph.type,omitempty"`
 }
 
+func TestClientInsertConcurrentUnique(t *testing.T) {
+	// the embedded engine checks unique predicates under its write lock
+	client, cleanup := CreateTestClient(t, "file://"+GetTempDir(t))
+	defer cleanup()
+
+	ctx := context.Background()
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = client.Insert(ctx, &TestEntity{Name: "Contended", CreatedAt: time.Now()})
+		}()
+	}
+	wg.Wait()
+
+	inserted := 0
+	for _, err := range errs {
+		if err == nil {
+			inserted++
+			continue
+		}
+		var uniqueErr *modusgraph.UniqueError
+		require.ErrorAs(t, err, &uniqueErr, "Rejected inserts should report the unique constraint")
+		require.Equal(t, "name", uniqueErr.Field)
+		require.Equal(t, "Contended", uniqueErr.Value)
+		require.Equal(t, "TestEntity", uniqueErr.NodeType)
+	}
+	require.Equal(t, 1, inserted, "Only one of the concurrent inserts should succeed")
+
+	var entities []TestEntity
+	require.NoError(t, client.Query(ctx, TestEntity{}).Nodes(&entities))
+	require.Len(t, entities, 1)
+}
+
 func TestEmbeddedInsert(t *testing.T) {
 	testCases := []struct {
 		name string
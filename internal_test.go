@@ -1,10 +1,14 @@
 package modusgraph
 
 import (
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	dg "github.com/dolan-in/dgman/v2"
+	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -126,82 +130,121 @@ func TestStructUpsertTagsProcessing(t *testing.T) {
 	}
 }
 
-func TestStructUniqueTagsProcessing(t *testing.T) {
+func TestGenerateUpsertQuery(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	query, vars, err := generateUpsertQuery(map[string]any{
+		"email":       `alice"@example.com`,
+		"employee_id": 123,
+		"score":       1.5,
+		"active":      true,
+		"created_at":  createdAt,
+	}, "User", false)
+	require.NoError(t, err)
 
-	tests := []struct {
-		name     string
-		input    any
-		expected []string
-	}{
-		{
-			"Single unique field",
-			struct {
-				Name string `json:"name" dgraph:"unique"`
-			}{},
-			[]string{"name"},
-		},
-		{
-			"Multiple unique fields",
-			struct {
-				Name  string `json:"name" dgraph:"unique"`
-				Email string `json:"email" dgraph:"unique"`
-			}{},
-			[]string{"name", "email"},
-		},
-		{
-			"No unique fields",
-			struct {
-				Name string `json:"name"`
-			}{},
-			[]string{},
-		},
-		{
-			"AllTags struct",
-			AllTags{},
-			[]string{"email", "my_user_id", "multi_tag", "employee_id"},
-		},
+	require.True(t, strings.Index(query, "query q(") == 0)
+	require.Contains(t, query, "$p0: bool")
+	require.Contains(t, query, "$p1: string")
+	require.Contains(t, query, "$p2: string")
+	require.Contains(t, query, "$p3: int")
+	require.Contains(t, query, "$p4: float")
+	require.Contains(t, query, "func: type(User)")
+	require.Contains(t, query, "eq(active, $p0) AND eq(created_at, $p1) AND eq(email, $p2) AND "+
+		"eq(employee_id, $p3) AND eq(score, $p4)")
+	require.Contains(t, query, upsertVar+" as uid")
+
+	require.Equal(t, "true", vars["$p0"])
+	require.Equal(t, "2024-03-01T12:30:00Z", vars["$p1"])
+	require.Equal(t, `alice"@example.com`, vars["$p2"])
+	require.Equal(t, "123", vars["$p3"])
+	require.Equal(t, "1.5", vars["$p4"])
+
+	query, _, err = generateUpsertQuery(map[string]any{"email": "alice@example.com"}, "User", true)
+	require.NoError(t, err)
+	require.Contains(t, query, "eq(email, $p0) AND NOT has(deletedAt)")
+
+	email, employeeID := "bob@example.com", 7
+	query, vars, err = generateUpsertQuery(map[string]any{
+		"email":       &email,
+		"employee_id": &employeeID,
+		"created_at":  &createdAt,
+	}, "User", false)
+	require.NoError(t, err)
+	require.Contains(t, query, "$p0: string, $p1: string, $p2: int")
+	require.Equal(t, map[string]string{"$p0": "2024-03-01T12:30:00Z", "$p1": "bob@example.com", "$p2": "7"},
+		vars, "Pointers should be followed")
+
+	_, _, err = generateUpsertQuery(map[string]any{"email": (*string)(nil)}, "User", false)
+	require.ErrorContains(t, err, `upsert predicate "email"`)
+	_, _, err = generateUpsertQuery(map[string]any{"created_at": (*time.Time)(nil)}, "User", false)
+	require.Error(t, err, "A nil time should not be formatted")
+}
+
+func TestVectorSchemaGeneration(t *testing.T) {
+
+	type Chunk struct {
+		Text      string    `json:"text,omitempty" dgraph:"index=term"`
+		Embedding []float32 `json:"embedding,omitempty" dgraph:"vector,index=hnsw,metric=cosine,exponent=4"`
+		Raw       []float32 `json:"raw,omitempty" dgraph:"vector"`
+		Scores    []float32 `json:"scores,omitempty"`
+	}
+	type Document struct {
+		Title  string    `json:"title,omitempty"`
+		Vector []float32 `json:"vector,omitempty" dgraph:"vector index=hnsw metric=euclidean"`
+		Chunks []*Chunk  `json:"chunks,omitempty"`
 	}
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			predMap := getUniquePredicates(tc.input)
-			preds := make([]string, 0, len(predMap))
-			for k := range predMap {
-				preds = append(preds, k)
-			}
-			sort.Strings(preds)
-			sort.Strings(tc.expected)
-			require.Equal(t, tc.expected, preds)
-		})
+	typeSchema := dg.NewTypeSchema()
+	typeSchema.Marshal("", &Document{})
+	require.NoError(t, applyVectorSchema(typeSchema, reflect.TypeOf(Document{}), make(map[reflect.Type]bool)))
+
+	require.Equal(t, `embedding: float32vector @index(hnsw(metric: "cosine", exponent: "4")) .`,
+		typeSchema.Schema["embedding"].String())
+	require.Equal(t, `raw: float32vector .`, typeSchema.Schema["raw"].String())
+	require.Equal(t, `vector: float32vector @index(hnsw(metric: "euclidean")) .`,
+		typeSchema.Schema["vector"].String())
+	require.Equal(t, `scores: [float] .`, typeSchema.Schema["scores"].String(),
+		"Fields without the vector tag should keep their list type")
+	require.Same(t, typeSchema.Schema["embedding"], typeSchema.Types["Chunk"]["embedding"])
+
+	type BadMetric struct {
+		Vector []float32 `json:"vector" dgraph:"vector,index=hnsw,metric=manhattan"`
 	}
-}
+	require.Error(t, applyVectorSchema(dg.NewTypeSchema(), reflect.TypeOf(BadMetric{}), make(map[reflect.Type]bool)))
 
-func TestGenerateFilterQuery(t *testing.T) {
-	obj := &AllTags{
-		Email:      "alice@example.com",
-		UserID:     "123",
-		EmployeeID: 123,
-		MultiTag:   "multi_tag",
-		DType:      []string{"User"},
+	type BadType struct {
+		Vector []float64 `json:"vector" dgraph:"vector"`
 	}
-	predicates := getUniquePredicates(obj)
-	query, vars := generateUniquePredicateQuery(predicates, obj.DType[0])
+	require.Error(t, applyVectorSchema(dg.NewTypeSchema(), reflect.TypeOf(BadType{}), make(map[reflect.Type]bool)))
+}
 
-	require.True(t, strings.Index(query, "query q(") == 0)
-	require.Contains(t, query, "$email: string")
-	require.Contains(t, query, "$my_user_id: string")
-	require.Contains(t, query, "$employee_id: int")
-	require.Contains(t, query, "func: type(User)")
-	require.Contains(t, query, "eq(email, $email)")
-	require.Contains(t, query, "eq(my_user_id, $my_user_id)")
-	require.Contains(t, query, "eq(employee_id, $employee_id)")
-	require.Contains(t, query, "OR")
-	require.Contains(t, query, "uid")
-
-	require.Equal(t, "alice@example.com", vars["$email"])
-	require.Equal(t, "123", vars["$my_user_id"])
-	require.Equal(t, "123", vars["$employee_id"])
-	require.Equal(t, "multi_tag", vars["$multi_tag"])
-
-	//fmt.Println(query)
+func TestChangeFeedCapture(t *testing.T) {
+	feed := newChangeFeed(0, logr.Discard())
+	require.False(t, feed.capturing(1), "Changes should not be captured without subscribers or retention")
+
+	s, err := feed.subscribe(ChangeFilter{})
+	require.NoError(t, err)
+	require.False(t, feed.capturing(1), "A transaction should not be captured in part")
+	require.True(t, feed.capturing(2))
+	feed.record(2, []changeRecord{{UID: 1, Predicate: "name"}})
+	feed.commit(1, 3)
+	feed.commit(2, 4)
+	require.Equal(t, []Change{{UID: 1, Predicate: "name", CommitTs: 4}}, s.queue)
+	require.Equal(t, uint64(4), feed.logStart)
+	require.Empty(t, feed.uncaptured)
+
+	// a subscriber that falls too far behind is dropped
+	changes := make([]changeRecord, maxQueuedChanges)
+	for i := range changes {
+		changes[i] = changeRecord{UID: uint64(i + 1), Predicate: "name"}
+	}
+	require.True(t, feed.capturing(5))
+	feed.record(5, changes)
+	feed.commit(5, 6)
+	require.Len(t, s.queue, 1, "The changes of a commit should be queued all or none")
+	require.NotContains(t, feed.subscribers, s)
+	select {
+	case <-s.done:
+	default:
+		require.Fail(t, "A subscriber falling too far behind should be dropped")
+	}
 }
@@ -0,0 +1,137 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// defaultPageSize is the number of objects Iterate fetches per query by default
+const defaultPageSize = 1000
+
+// WithPageSize sets the number of objects Iterate and Scan fetch per query. It
+// defaults to 1000.
+func WithPageSize(n int) QueryOpt {
+	return func(o *queryOptions) {
+		o.pageSize = n
+	}
+}
+
+// Iterate returns an iterator over all objects of type T that match the options,
+// without loading them all in memory. Objects are fetched in pages ordered by UID,
+// using the UID of the last object of a page as the cursor of the next one, and all
+// pages are read from the same snapshot of the database, so writes committed during
+// the iteration are not observed. T must have a UID string field.
+//
+// WithFilter, WithDepth and WithPageSize apply, and WithFirst limits the total
+// number of objects. Ordering and offsets are not supported. The iteration stops
+// after the first error, which is yielded with the zero value of T.
+//
+// Example:
+//
+//	for user, err := range modusgraph.Iterate[User](ctx, client) {
+//		if err != nil {
+//			return err
+//		}
+//		reindex(user)
+//	}
+func Iterate[T any](ctx context.Context, c Client, opts ...QueryOpt) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		stopped := false
+		err := intercept(ctx, c, "Iterate", new(T), func(ctx context.Context) error {
+			return iterate(ctx, c, opts, func(obj T) bool {
+				stopped = !yield(obj, nil)
+				return !stopped
+			})
+		})
+		if err != nil && !stopped {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// iterate calls yield with each object of type T that matches the options, until it
+// returns false.
+func iterate[T any](ctx context.Context, c Client, opts []QueryOpt, yield func(T) bool) error {
+	options := newQueryOptions(c, opts)
+	if len(options.orders) > 0 || options.offset > 0 {
+		return errors.New("iterate does not support ordering or offsets, objects are ordered by UID")
+	}
+	if field, ok := reflect.TypeFor[T]().FieldByName("UID"); !ok || field.Type.Kind() != reflect.String {
+		return fmt.Errorf("type %s must have a UID string field to be iterated", reflect.TypeFor[T]())
+	}
+	pageSize := options.pageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	dgoClient, cleanup, err := c.DgraphClient()
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	// a read-only transaction reads every page at the timestamp of the first one
+	tx := dg.NewReadOnlyTxnContext(ctx, dgoClient)
+	after := ""
+	count := 0
+	for {
+		size := pageSize
+		if options.first > 0 {
+			size = min(size, options.first-count)
+		}
+
+		var model T
+		q := tx.Get(&model).All(options.depth).First(size)
+		if options.filter != "" {
+			q.Filter(options.filter)
+		}
+		if len(options.vars) > 0 {
+			q.Vars(varsDefinition("iterate", options.vars), options.vars)
+		}
+		if after != "" {
+			q.After(after)
+		}
+		var page []T
+		if err := q.Nodes(&page); err != nil {
+			return err
+		}
+
+		for _, obj := range page {
+			if !yield(obj) {
+				return nil
+			}
+		}
+		count += len(page)
+		if len(page) < size || (options.first > 0 && count >= options.first) {
+			return nil
+		}
+		after = getUIDValue(&page[len(page)-1])
+	}
+}
+
+// Scan calls fn for each object of type T that matches the options, in the same way
+// as Iterate. It stops at the first error returned by fn or by a query.
+func Scan[T any](ctx context.Context, c Client, fn func(T) error, opts ...QueryOpt) error {
+	return intercept(ctx, c, "Scan", new(T), func(ctx context.Context) error {
+		var fnErr error
+		err := iterate(ctx, c, opts, func(obj T) bool {
+			fnErr = fn(obj)
+			return fnErr == nil
+		})
+		if err != nil {
+			return err
+		}
+		return fnErr
+	})
+}
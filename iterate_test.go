@@ -0,0 +1,115 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterate(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "IterateWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "IterateWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			const total = 25
+			entities := make([]*TestEntity, total)
+			for i := range entities {
+				entities[i] = &TestEntity{
+					Name:        fmt.Sprintf("Entity %02d", i),
+					Description: fmt.Sprintf("Iterated entity %d", i%2),
+					CreatedAt:   time.Now(),
+				}
+			}
+			require.NoError(t, client.Insert(ctx, entities), "Insert should succeed")
+
+			seen := make(map[string]bool)
+			for entity, err := range modusgraph.Iterate[TestEntity](ctx, client, modusgraph.WithPageSize(10)) {
+				require.NoError(t, err, "Iterate should succeed")
+				require.False(t, seen[entity.UID], "Each entity should be yielded once")
+				seen[entity.UID] = true
+
+				if len(seen) == 1 {
+					// writes committed during the iteration are not observed
+					require.NoError(t, client.Insert(ctx, &TestEntity{Name: "Late Entity", CreatedAt: time.Now()}))
+				}
+			}
+			require.Len(t, seen, total, "Iterate should yield every entity from the snapshot")
+
+			count := 0
+			err := modusgraph.Scan(ctx, client, func(entity TestEntity) error {
+				require.Equal(t, "Iterated entity 1", entity.Description)
+				count++
+				return nil
+			}, modusgraph.WithFilter("eq(description, $desc)", map[string]string{"$desc": "Iterated entity 1"}),
+				modusgraph.WithPageSize(5))
+			require.NoError(t, err, "Scan with a filter should succeed")
+			require.Equal(t, 12, count, "Scan should visit the matching entities")
+
+			count = 0
+			for _, err := range modusgraph.Iterate[TestEntity](ctx, client,
+				modusgraph.WithPageSize(4), modusgraph.WithFirst(9)) {
+				require.NoError(t, err)
+				count++
+			}
+			require.Equal(t, 9, count, "WithFirst should limit the total number of entities")
+
+			count = 0
+			for _, err := range modusgraph.Iterate[TestEntity](ctx, client, modusgraph.WithPageSize(3)) {
+				require.NoError(t, err)
+				count++
+				if count == 5 {
+					break
+				}
+			}
+			require.Equal(t, 5, count, "Breaking out of the loop should stop the iteration")
+
+			errStop := errors.New("stop")
+			count = 0
+			err = modusgraph.Scan(ctx, client, func(entity TestEntity) error {
+				count++
+				return errStop
+			})
+			require.ErrorIs(t, err, errStop, "Scan should return the error of the callback")
+			require.Equal(t, 1, count)
+
+			for _, err := range modusgraph.Iterate[TestEntity](ctx, client, modusgraph.WithOrderAsc("name")) {
+				require.Error(t, err, "Ordering should be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// Migration is a versioned change of the schema and data of the database. The migrations
+// registered with WithMigrations are applied once each, in version order, by Migrate.
+type Migration struct {
+	// Version orders the migrations. It must be positive and unique.
+	Version int64
+	// Name describes the migration in its record and in MigrationStatus.
+	Name string
+	// Schema is DQL schema applied before Up, e.g. "email: string @index(hash) .".
+	Schema string
+	// Up transforms the data. It runs in the transaction that records the migration as
+	// applied, so either both are committed or neither is.
+	Up func(ctx context.Context, tx Txn) error
+}
+
+// MigrationState is the state of a migration, as reported by MigrationStatus.
+type MigrationState struct {
+	Version int64
+	Name    string
+	// Applied reports whether the migration was applied
+	Applied bool
+	// AppliedAt is the time the migration was applied at, if it was
+	AppliedAt time.Time
+	// Registered reports whether the migration was registered with WithMigrations. A
+	// migration applied by another version of the application may not be.
+	Registered bool
+}
+
+// WithMigrations registers migrations for Migrate, MigrateTo and MigrationStatus. It can
+// be passed several times to register more migrations. Clients for the same URI and other
+// options share their connections, each with its own migrations.
+func WithMigrations(migrations ...Migration) ClientOpt {
+	return func(o *clientOptions) {
+		o.migrations = append(o.migrations, migrations...)
+	}
+}
+
+const (
+	// migrationVersionPredicate holds the version of an applied migration, on its record
+	migrationVersionPredicate = "modusgraph.migration.version"
+	// migrationNamePredicate holds the name of an applied migration, on its record
+	migrationNamePredicate = "modusgraph.migration.name"
+	// migrationAppliedAtPredicate holds the time a migration was applied at, on its record
+	migrationAppliedAtPredicate = "modusgraph.migration.appliedAt"
+	// migrationLockPredicate identifies the node of the migration lock
+	migrationLockPredicate = "modusgraph.migration.lock"
+	// migrationLockedByPredicate holds the ID of the process holding the migration lock
+	migrationLockedByPredicate = "modusgraph.migration.lockedBy"
+	// migrationLockedUntilPredicate holds the time the migration lock expires at, unless
+	// it is renewed by its holder
+	migrationLockedUntilPredicate = "modusgraph.migration.lockedUntil"
+
+	// migrationLockName is the value of migrationLockPredicate on the lock node
+	migrationLockName = "migrations"
+)
+
+// migrationSchema indexes the predicates of the migration records and lock. The @upsert
+// directives make concurrent writes of the same version or of the lock conflict.
+const migrationSchema = migrationVersionPredicate + ": int @index(int) @upsert .\n" +
+	migrationNamePredicate + ": string .\n" +
+	migrationAppliedAtPredicate + ": datetime .\n" +
+	migrationLockPredicate + ": string @index(exact) @upsert .\n" +
+	migrationLockedByPredicate + ": string .\n" +
+	migrationLockedUntilPredicate + ": datetime .\n"
+
+var (
+	// migrationLockTTL is how long the migration lock is held without being renewed, so
+	// that a process stopping while it migrates doesn't block the others for good
+	migrationLockTTL = 30 * time.Second
+	// migrationLockPoll is how often a process waiting for the migration lock retries
+	migrationLockPoll = 250 * time.Millisecond
+)
+
+// errMigrationLockLost is returned when the migration lock expired before it was renewed
+// and another process acquired it.
+var errMigrationLockLost = errors.New("migration lock lost to another process")
+
+// Migrate implements applying the registered migrations that were not applied yet.
+func (c client) Migrate(ctx context.Context) error {
+	return c.intercept(ctx, "Migrate", nil, func(ctx context.Context) error {
+		return c.migrateTo(ctx, math.MaxInt64)
+	})
+}
+
+// MigrateTo implements applying the registered migrations up to the given version.
+func (c client) MigrateTo(ctx context.Context, version int64) error {
+	return c.intercept(ctx, "MigrateTo", version, func(ctx context.Context) error {
+		migrations, err := sortedMigrations(c.options.migrations)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == version }) {
+			return fmt.Errorf("no migration with version %d is registered", version)
+		}
+		return c.migrateTo(ctx, version)
+	})
+}
+
+// MigrationStatus implements reporting the state of the migrations.
+func (c client) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	var states []MigrationState
+	err := c.intercept(ctx, "MigrationStatus", nil, func(ctx context.Context) error {
+		migrations, err := sortedMigrations(c.options.migrations)
+		if err != nil {
+			return err
+		}
+		if err := c.alter(ctx, migrationSchema); err != nil {
+			return err
+		}
+		applied, err := c.appliedMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			state := MigrationState{Version: m.Version, Name: m.Name, Registered: true}
+			if record, ok := applied[m.Version]; ok {
+				state.Applied = true
+				state.AppliedAt = record.AppliedAt
+				delete(applied, m.Version)
+			}
+			states = append(states, state)
+		}
+		for _, record := range applied {
+			states = append(states, MigrationState{
+				Version:   record.Version,
+				Name:      record.Name,
+				Applied:   true,
+				AppliedAt: record.AppliedAt,
+			})
+		}
+		slices.SortFunc(states, func(a, b MigrationState) int { return cmp.Compare(a.Version, b.Version) })
+		return nil
+	})
+	return states, err
+}
+
+// sortedMigrations returns migrations sorted by version, after checking their versions.
+func sortedMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q must have a positive version", m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migrations %q and %q have the same version %d",
+				sorted[i-1].Name, m.Name, m.Version)
+		}
+	}
+	return sorted, nil
+}
+
+// migrateTo applies the registered migrations up to version that were not applied yet,
+// while holding the migration lock.
+func (c client) migrateTo(ctx context.Context, version int64) error {
+	migrations, err := sortedMigrations(c.options.migrations)
+	if err != nil {
+		return err
+	}
+	if err := c.alter(ctx, migrationSchema); err != nil {
+		return err
+	}
+	lock, err := c.lockMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.release(context.WithoutCancel(ctx))
+
+	// the records are read once the lock is held, to skip the migrations applied by the
+	// process that held it before
+	applied, err := c.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	for v := range applied {
+		if v > version {
+			return fmt.Errorf("migration %d is already applied, migrations can't be reverted", v)
+		}
+	}
+	for _, m := range migrations {
+		if m.Version > version {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := lock.err(); err != nil {
+			return err
+		}
+		if err := c.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+	}
+	return lock.err()
+}
+
+// applyMigration applies the schema of m, then runs its Up function and records it as
+// applied in a single transaction.
+func (c client) applyMigration(ctx context.Context, m Migration) error {
+	c.logger.V(1).Info("Applying migration", "version", m.Version, "name", m.Name)
+	if m.Schema != "" {
+		if err := c.alter(ctx, m.Schema); err != nil {
+			return err
+		}
+	}
+	t, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = t.finish(ctx, false) }()
+
+	if m.Up != nil {
+		if err := m.Up(ctx, t); err != nil {
+			return err
+		}
+	}
+	record, err := json.Marshal(map[string]any{
+		"uid":                       "_:migration",
+		migrationVersionPredicate:   m.Version,
+		migrationNamePredicate:      m.Name,
+		migrationAppliedAtPredicate: time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	err = t.run(ctx, func() error {
+		_, err := t.tx.Txn().Mutate(ctx, &api.Mutation{SetJson: record})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return t.finish(ctx, true)
+}
+
+// migrationRecord is the record of an applied migration.
+type migrationRecord struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"appliedAt"`
+}
+
+// appliedMigrations returns the records of the applied migrations, by version.
+func (c client) appliedMigrations(ctx context.Context) (map[int64]migrationRecord, error) {
+	q := fmt.Sprintf(`{
+  applied(func: has(<%s>)) {
+    version: <%s>
+    name: <%s>
+    appliedAt: <%s>
+  }
+}`, migrationVersionPredicate, migrationVersionPredicate, migrationNamePredicate, migrationAppliedAtPredicate)
+	data, err := c.queryRaw(ctx, nil, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Applied []migrationRecord `json:"applied"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]migrationRecord, len(result.Applied))
+	for _, record := range result.Applied {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// alter applies the DQL schema sch.
+func (c client) alter(ctx context.Context, sch string) error {
+	client, err := c.pool.get()
+	if err != nil {
+		c.logger.Error(err, "Failed to get client from pool")
+		return err
+	}
+	defer c.pool.put(client)
+
+	return client.Alter(ctx, &api.Operation{Schema: sch})
+}
+
+// migrationLock is the migration lock held by the client, which is renewed in the
+// background until it is released.
+type migrationLock struct {
+	c      client
+	holder string
+	stop   chan struct{}
+	done   chan struct{}
+
+	mutex   sync.Mutex
+	lostErr error
+}
+
+// lockMigrations acquires the migration lock, waiting for the process holding it, if any,
+// to release it or to stop renewing it.
+func (c client) lockMigrations(ctx context.Context) (*migrationLock, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	lock := &migrationLock{
+		c:      c,
+		holder: hex.EncodeToString(id),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for {
+		acquired, err := lock.write(ctx, false)
+		if err != nil && !errors.Is(err, dgo.ErrAborted) {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+		c.logger.V(1).Info("Waiting for the migration lock")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(migrationLockPoll):
+		}
+	}
+	go lock.renew()
+	return lock, nil
+}
+
+// write sets the lock as held by lock.holder until migrationLockTTL from now, if it is
+// not held by another process, and reports whether it was. If release is true, the lock
+// is released instead, if lock.holder holds it.
+func (lock *migrationLock) write(ctx context.Context, release bool) (bool, error) {
+	now := time.Now().UTC()
+	var written bool
+	err := lock.c.withTxn(ctx, nil, func(txn *dg.TxnContext) error {
+		q := fmt.Sprintf(`{
+  lock(func: eq(<%s>, %q)) {
+    uid
+    lockedBy: <%s>
+    lockedUntil: <%s>
+  }
+}`, migrationLockPredicate, migrationLockName, migrationLockedByPredicate, migrationLockedUntilPredicate)
+		resp, err := txn.Txn().Query(ctx, q)
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Lock []struct {
+				UID         string    `json:"uid"`
+				LockedBy    string    `json:"lockedBy"`
+				LockedUntil time.Time `json:"lockedUntil"`
+			} `json:"lock"`
+		}
+		if err := json.Unmarshal(resp.GetJson(), &result); err != nil {
+			return err
+		}
+		uid := "_:lock"
+		if len(result.Lock) > 0 {
+			held := result.Lock[0]
+			uid = held.UID
+			if held.LockedBy != lock.holder && held.LockedUntil.After(now) {
+				return txn.Txn().Discard(ctx)
+			}
+			if release && held.LockedBy != lock.holder {
+				return txn.Txn().Discard(ctx)
+			}
+		} else if release {
+			return txn.Txn().Discard(ctx)
+		}
+		until := now.Add(migrationLockTTL)
+		if release {
+			until = now
+		}
+		set, err := json.Marshal(map[string]any{
+			"uid":                         uid,
+			migrationLockPredicate:        migrationLockName,
+			migrationLockedByPredicate:    lock.holder,
+			migrationLockedUntilPredicate: until.Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			return err
+		}
+		// concurrent writes of the lock conflict, so only one of them is committed
+		if _, err := txn.Txn().Mutate(ctx, &api.Mutation{SetJson: set, CommitNow: true}); err != nil {
+			return err
+		}
+		written = true
+		return nil
+	})
+	return written, err
+}
+
+// renew renews the lock until it is released, recording that it was lost if another
+// process acquired it meanwhile.
+func (lock *migrationLock) renew() {
+	defer close(lock.done)
+	ticker := time.NewTicker(migrationLockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lock.stop:
+			return
+		case <-ticker.C:
+		}
+		renewed, err := lock.write(context.Background(), false)
+		if err != nil {
+			// the lock is still held until it expires, so renewing is retried
+			lock.c.logger.Error(err, "Failed to renew the migration lock")
+			continue
+		}
+		if !renewed {
+			lock.mutex.Lock()
+			lock.lostErr = errMigrationLockLost
+			lock.mutex.Unlock()
+			return
+		}
+	}
+}
+
+// err returns errMigrationLockLost if the lock was acquired by another process.
+func (lock *migrationLock) err() error {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+	return lock.lostErr
+}
+
+// release stops renewing the lock and releases it.
+func (lock *migrationLock) release(ctx context.Context) {
+	close(lock.stop)
+	<-lock.done
+	if lock.err() != nil {
+		return
+	}
+	if _, err := lock.write(ctx, true); err != nil {
+		lock.c.logger.Error(err, "Failed to release the migration lock")
+	}
+}
@@ -0,0 +1,249 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+type MigratedUser struct {
+	Name  string `json:"name,omitempty" dgraph:"index=exact"`
+	Email string `json:"email,omitempty" dgraph:"index=hash"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+var errMigrationFailed = errors.New("migration failed")
+
+// userMigrations returns migrations adding users, lowercasing their emails, then failing.
+func userMigrations() []modusgraph.Migration {
+	return []modusgraph.Migration{
+		{
+			Version: 2,
+			Name:    "lowercase emails",
+			Up: func(ctx context.Context, tx modusgraph.Txn) error {
+				data, err := tx.QueryRaw(ctx, `{ users(func: has(email)) { uid email } }`, nil)
+				if err != nil {
+					return err
+				}
+				var result struct {
+					Users []MigratedUser `json:"users"`
+				}
+				if err := json.Unmarshal(data, &result); err != nil {
+					return err
+				}
+				for _, user := range result.Users {
+					user.Email = strings.ToLower(user.Email)
+					if err := tx.UpdateFields(ctx, &user, "email"); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version: 1,
+			Name:    "add users",
+			Schema:  "email: string @index(hash) .",
+			Up: func(ctx context.Context, tx modusgraph.Txn) error {
+				return tx.Insert(ctx, []*MigratedUser{
+					{Name: "Ada", Email: "Ada@Example.com"},
+					{Name: "Alan", Email: "ALAN@example.com"},
+				})
+			},
+		},
+		{
+			Version: 3,
+			Name:    "broken",
+			Up: func(ctx context.Context, tx modusgraph.Txn) error {
+				if err := tx.Insert(ctx, &MigratedUser{Name: "Grace", Email: "grace@example.com"}); err != nil {
+					return err
+				}
+				return errMigrationFailed
+			},
+		},
+	}
+}
+
+// countUsers returns the number of users with the given email, or with any email if it
+// is empty.
+func countUsers(t *testing.T, client modusgraph.Client, email string) int {
+	t.Helper()
+	q := `{ users(func: has(email)) { uid } }`
+	if email != "" {
+		q = `{ users(func: eq(email, "` + email + `")) { uid } }`
+	}
+	data, err := client.QueryRaw(context.Background(), q, nil)
+	require.NoError(t, err)
+	var result struct {
+		Users []MigratedUser `json:"users"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	return len(result.Users)
+}
+
+func TestClientMigrations(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "MigrationsWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "MigrationsWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri, modusgraph.WithMigrations(userMigrations()...))
+			defer cleanup()
+			ctx := context.Background()
+
+			status, err := client.MigrationStatus(ctx)
+			require.NoError(t, err)
+			require.Len(t, status, 3)
+			for i, state := range status {
+				require.Equal(t, int64(i+1), state.Version, "Migrations should be ordered by version")
+				require.False(t, state.Applied)
+				require.True(t, state.Registered)
+			}
+
+			require.Error(t, client.MigrateTo(ctx, 4), "Only registered versions can be migrated to")
+			require.NoError(t, client.MigrateTo(ctx, 1))
+			require.Equal(t, 1, countUsers(t, client, "Ada@Example.com"), "The schema should be applied")
+
+			err = client.Migrate(ctx)
+			require.ErrorIs(t, err, errMigrationFailed)
+			require.Contains(t, err.Error(), "broken")
+			require.Equal(t, 1, countUsers(t, client, "ada@example.com"))
+			require.Equal(t, 1, countUsers(t, client, "alan@example.com"))
+			require.Equal(t, 2, countUsers(t, client, ""), "A failed migration should be rolled back")
+
+			status, err = client.MigrationStatus(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []bool{true, true, false},
+				[]bool{status[0].Applied, status[1].Applied, status[2].Applied})
+			require.Equal(t, "lowercase emails", status[1].Name)
+			require.False(t, status[0].AppliedAt.IsZero())
+
+			require.Error(t, client.MigrateTo(ctx, 1), "Applied migrations can't be reverted")
+			require.NoError(t, client.MigrateTo(ctx, 2), "Applied migrations should be skipped")
+		})
+	}
+}
+
+func TestClientMigrateConcurrently(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "MigrateConcurrentlyWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "MigrateConcurrentlyWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			var runs [3]atomic.Int32
+			var migrations []modusgraph.Migration
+			for i := range runs {
+				migrations = append(migrations, modusgraph.Migration{
+					Version: int64(i + 1),
+					Up: func(ctx context.Context, tx modusgraph.Txn) error {
+						runs[i].Add(1)
+						return tx.Insert(ctx, &MigratedUser{Name: "user", Email: "user@example.com"})
+					},
+				})
+			}
+			client, cleanup := CreateTestClient(t, tc.uri, modusgraph.WithMigrations(migrations...))
+			defer cleanup()
+			ctx := context.Background()
+
+			var wg sync.WaitGroup
+			errs := make([]error, 4)
+			for i := range errs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs[i] = client.Migrate(ctx)
+				}()
+			}
+			wg.Wait()
+			for _, err := range errs {
+				require.NoError(t, err)
+			}
+			for i := range runs {
+				require.Equal(t, int32(1), runs[i].Load(), "Each migration should run once")
+			}
+			require.Equal(t, 3, countUsers(t, client, ""))
+		})
+	}
+}
+
+func TestLocalClientMigrationsSharedEngine(t *testing.T) {
+	path := GetTempDir(t)
+	ctx := context.Background()
+
+	migrations := userMigrations()
+	client, cleanup := CreateTestClient(t, "file://"+path, modusgraph.WithMigrations(migrations[1]))
+	defer cleanup()
+	otherClient, err := modusgraph.NewClient("file://"+path, modusgraph.WithAutoSchema(true),
+		modusgraph.WithMigrations(migrations[:2]...))
+	require.NoError(t, err, "Clients with migrations should share the engine of the path")
+
+	require.NoError(t, client.Migrate(ctx))
+	status, err := otherClient.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 2)
+	require.True(t, status[0].Applied, "Migrations applied by one client should be seen by the other")
+	require.True(t, status[1].Registered)
+	require.False(t, status[1].Applied)
+
+	status, err = client.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 1, "Each client should only see its own migrations")
+
+	require.NoError(t, otherClient.Migrate(ctx))
+	otherClient.Close()
+	require.Equal(t, 1, countUsers(t, client, "ada@example.com"),
+		"Closing the other client should leave the shared connections open")
+}
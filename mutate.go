@@ -11,12 +11,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/dgraph-io/dgo/v250/protos/api"
 	dg "github.com/dolan-in/dgman/v2"
 )
 
+// deleteAllValues is the object of a delete nquad that removes every value of its predicate
+const deleteAllValues = "_STAR_ALL"
+
+// upsertVar is the query variable holding the UID of the node an upsert block updates
+const upsertVar = "upsert"
+
 // checkObject validates the passed obj. If it's a slice or a pointer
 // to a slice, it returns the first element of the slice. Ultimately,
 // the object discovered must be pointer.
@@ -50,7 +61,37 @@ func checkObject(obj any) (any, error) {
 	return obj, nil
 }
 
-func (c client) process(ctx context.Context,
+// withTxn runs fn against tx. If tx is nil, a new transaction is created from the
+// pool that commits along with its first mutation. A violated unique constraint is
+// returned as a *UniqueError.
+func (c client) withTxn(ctx context.Context, tx *dg.TxnContext, fn func(*dg.TxnContext) error) error {
+	if tx != nil {
+		return asUniqueError(fn(tx))
+	}
+	client, err := c.pool.get()
+	if err != nil {
+		c.logger.Error(err, "Failed to get client from pool")
+		return err
+	}
+	defer c.pool.put(client)
+
+	return asUniqueError(fn(dg.NewTxnContext(ctx, client).SetCommitNow()))
+}
+
+// mutate applies mu in tx, or in a new transaction committed right away if tx is nil.
+func (c client) mutate(ctx context.Context, tx *dg.TxnContext, mu *api.Mutation) error {
+	mu.CommitNow = tx == nil
+	return c.withTxn(ctx, tx, func(tx *dg.TxnContext) error {
+		resp, err := tx.Txn().Mutate(ctx, mu)
+		if err != nil {
+			return err
+		}
+		c.logger.V(2).Info("Mutation successful", "uidCount", len(resp.GetUids()))
+		return nil
+	})
+}
+
+func (c client) process(ctx context.Context, tx *dg.TxnContext,
 	obj any, operation string,
 	txFunc func(*dg.TxnContext, any) ([]string, error)) error {
 
@@ -58,215 +99,306 @@ func (c client) process(ctx context.Context,
 	if err != nil {
 		return err
 	}
+	if err := beforeHooks(ctx, operation, obj); err != nil {
+		return err
+	}
+	af, err := autoFieldsOf(obj)
+	if err != nil {
+		return err
+	}
+	switch operation {
+	case "Insert":
+		af.stamp(obj, true)
+	case "Update":
+		af.stamp(obj, false)
+	}
+	if err := validateObject(obj, nil); err != nil {
+		return err
+	}
 	if c.options.autoSchema {
-		err := c.UpdateSchema(ctx, schemaObj)
+		err := c.updateSchema(ctx, schemaObj)
 		if err != nil {
 			return err
 		}
 	}
 
-	client, err := c.pool.get()
-	if err != nil {
-		c.logger.Error(err, "Failed to get client from pool")
-		return err
-	}
-	defer c.pool.put(client)
-
-	tx := dg.NewTxnContext(ctx, client).SetCommitNow()
-	uids, err := txFunc(tx, obj)
+	err = c.withTxn(ctx, tx, func(tx *dg.TxnContext) error {
+		uids, err := txFunc(tx, obj)
+		if err != nil {
+			// dgman names new nodes with blank UIDs before mutating them
+			clearBlankUIDs(obj)
+			return err
+		}
+		c.logger.V(2).Info(operation+" successful", "uidCount", len(uids))
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	c.logger.V(2).Info(operation+" successful", "uidCount", len(uids))
-	return nil
+	return afterHooks(ctx, operation, obj)
 }
 
-func (c client) mutateWithUniqueVerification(ctx context.Context, obj any, insert bool) error {
+// upsert inserts obj, or updates the node of the same type whose predicates all have
+// the same values as obj. If no predicates are passed, the first predicate with the
+// `upsert` tag is used.
+func (c client) upsert(ctx context.Context, tx *dg.TxnContext, obj any, predicates []string) error {
 
 	schemaObj, err := checkObject(obj)
 	if err != nil {
 		return err
 	}
+	if err := beforeHooks(ctx, "Upsert", obj); err != nil {
+		return err
+	}
+	af, err := autoFieldsOf(obj)
+	if err != nil {
+		return err
+	}
+	// the create time and version are set once the node is known to be created
+	af.stamp(obj, false)
+	if err := validateObject(obj, nil); err != nil {
+		return err
+	}
 	if c.options.autoSchema {
-		if err := c.UpdateSchema(ctx, schemaObj); err != nil {
+		err := c.updateSchema(ctx, schemaObj)
+		if err != nil {
 			return err
 		}
 	}
 
-	// Get the value and prepare for unified slice processing
+	// users can pass slices, so check for that
 	val := reflect.ValueOf(obj)
-	var sliceValue reflect.Value
-
-	// Handle pointer to slice
 	if val.Kind() == reflect.Ptr && val.Elem().Kind() == reflect.Slice {
-		sliceValue = val.Elem()
-	} else if val.Kind() == reflect.Slice {
-		// Direct slice
-		sliceValue = val
-	} else {
-		// Single object - create a slice with one element
-		valElem := val
-		for valElem.Kind() == reflect.Ptr {
-			valElem = valElem.Elem()
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice {
+		return c.upsertObject(ctx, tx, obj, predicates)
+	}
+	for i := 0; i < val.Len(); i++ {
+		if err := c.upsertObject(ctx, tx, val.Index(i).Interface(), predicates); err != nil {
+			return err
 		}
-		sliceType := reflect.SliceOf(valElem.Type())
-		sliceValue = reflect.MakeSlice(sliceType, 1, 1)
-		sliceValue.Index(0).Set(valElem)
 	}
+	return nil
+}
 
-	seen := make(map[string]int)
-	for i := 0; i < sliceValue.Len(); i++ {
-		elem := sliceValue.Index(i).Interface()
-		preds := getUniquePredicates(elem)
-		if len(preds) == 0 {
-			continue
-		}
-		// In-memory duplicate check
-		sig := ""
-		for k, v := range preds {
-			sig += fmt.Sprintf("%s=%v;", k, v)
+// upsertObject upserts the single object obj, then calls its AfterInsert hook.
+func (c client) upsertObject(ctx context.Context, tx *dg.TxnContext, obj any, predicates []string) error {
+	var values map[string]any
+	var err error
+	if len(predicates) == 0 {
+		values = getUpsertPredicates(obj, true)
+		if len(values) == 0 {
+			return errors.New("no upsert predicates found")
 		}
-		if prev, ok := seen[sig]; ok {
-			return fmt.Errorf("duplicate unique predicates in slice at indices %d and %d", prev, i)
-		}
-		seen[sig] = i
-		// Persistent uniqueness check
-		nodeType := getNodeType(elem)
-		query, vars := generateUniquePredicateQuery(preds, nodeType)
-		resp, err := c.QueryRaw(ctx, query, vars)
-		if err != nil {
-			return err
-		}
-		uid, err := extractUIDFromDgraphQueryResult(resp)
+	} else {
+		values, err = getPredicateValues(obj, predicates)
 		if err != nil {
 			return err
 		}
-		if uid != "" && (insert || uid != getUIDValue(elem)) {
-			return &dg.UniqueError{NodeType: nodeType, UID: uid}
-		}
 	}
 
-	client, err := c.pool.get()
+	query, vars, err := generateUpsertQuery(values, getNodeType(obj), c.options.softDelete)
 	if err != nil {
-		c.logger.Error(err, "Failed to get client from pool")
 		return err
 	}
-	defer c.pool.put(client)
-
-	tx := dg.NewTxnContext(ctx, client).SetCommitNow()
-	uids, err := tx.MutateBasic(obj)
+	af, err := autoFieldsOf(obj)
 	if err != nil {
 		return err
 	}
-	c.logger.V(2).Info("mutation successful", "uidCount", len(uids))
-	return nil
-}
-
-func (c client) upsert(ctx context.Context, obj any, upsertPredicate string) error {
-
-	schemaObj, err := checkObject(obj)
+	err = c.withTxn(ctx, tx, func(txn *dg.TxnContext) error {
+		if len(af.createTime) == 0 && af.version == nil {
+			_, err := c.upsertBlock(ctx, txn, tx == nil, obj, query, vars, "")
+			return err
+		}
+		// the node is looked up first, in the same transaction, to know whether it is
+		// created or updated with a compare-and-set of its version
+		// the variable is dropped, as a plain query must use the variables it defines
+		lookup := strings.Replace(query, upsertVar+" as uid", "uid", 1)
+		resp, err := txn.Txn().QueryWithVars(ctx, lookup, vars)
+		if err != nil {
+			return err
+		}
+		uid, err := extractUIDFromDgraphQueryResult(resp.GetJson())
+		if err != nil {
+			return err
+		}
+		if uid == "" || af.version == nil {
+			af.stamp(obj, uid == "")
+			_, err := c.upsertBlock(ctx, txn, tx == nil, obj, query, vars, "")
+			return err
+		}
+		uidField := reflect.ValueOf(obj).Elem().FieldByName("UID")
+		original := uidField.String()
+		uidField.SetString(uid)
+		if err := c.updateVersioned(ctx, txn, tx == nil, obj, af); err != nil {
+			uidField.SetString(original)
+			return err
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	return afterHooks(ctx, "Upsert", obj)
+}
 
-	// users can pass slices, so check for that
-	val := reflect.ValueOf(obj)
-	var sliceValue reflect.Value
-
-	// Handle pointer to slice
-	if val.Kind() == reflect.Ptr && val.Elem().Kind() == reflect.Slice {
-		sliceValue = val.Elem()
-	} else if val.Kind() == reflect.Slice {
-		// Direct slice
-		sliceValue = val
-	}
-	if sliceValue.IsValid() && sliceValue.Len() > 0 {
-		for i := 0; i < sliceValue.Len(); i++ {
-			elem := sliceValue.Index(i).Interface()
-			err := c.upsert(ctx, elem, upsertPredicate)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	} else {
-		if c.options.autoSchema {
-			err := c.UpdateSchema(ctx, schemaObj)
-			if err != nil {
-				return err
-			}
+// upsertBlock inserts or updates obj in txn with a single upsert block, so that the
+// lookup of the existing node and the mutation are atomic, and returns the results of
+// its query. The query must define the variable upsertVar holding the UID of the node
+// to update, which is empty to insert obj, in its q block. The mutation is only applied
+// if cond, if given, holds.
+func (c client) upsertBlock(ctx context.Context, txn *dg.TxnContext, commitNow bool, obj any,
+	query string, vars map[string]string, cond string) ([]byte, error) {
+
+	if err := dg.SetTypes(obj); err != nil {
+		return nil, err
+	}
+	// new nodes are given blank node names, to set the UIDs assigned to them afterwards
+	nextBlank := 0
+	walkUIDs(reflect.ValueOf(obj), func(uid reflect.Value) {
+		if uid.String() == "" {
+			nextBlank++
+			uid.SetString(fmt.Sprintf("_:node%d", nextBlank))
 		}
+	})
+	uidField := reflect.ValueOf(obj).Elem().FieldByName("UID")
+	uidField.SetString("uid(" + upsertVar + ")")
+	setJSON, err := resultJSON.Marshal(obj)
+	if err != nil {
+		clearBlankUIDs(obj)
+		return nil, err
 	}
 
-	upsertPredicates := getUpsertPredicates(schemaObj, upsertPredicate == "")
-	if len(upsertPredicates) == 0 {
-		return errors.New("no upsert predicates found")
+	req := &api.Request{
+		Query:     query,
+		Vars:      vars,
+		Mutations: []*api.Mutation{{SetJson: setJSON, Cond: cond}},
+		CommitNow: commitNow,
 	}
-	var firstPredicate string
-	for k := range upsertPredicates {
-		firstPredicate = k
-		break
+	resp, err := txn.Txn().Do(ctx, req)
+	if err != nil {
+		clearBlankUIDs(obj)
+		return nil, err
 	}
-	if len(upsertPredicates) > 1 {
-		c.logger.V(1).Info("Multiple upsert predicates found, using first", "predicate", firstPredicate)
+
+	uids := maps.Clone(resp.GetUids())
+	if uids == nil {
+		uids = make(map[string]string)
 	}
-	if upsertPredicate == "" {
-		upsertPredicate = firstPredicate
-	} else {
-		if _, ok := upsertPredicates[upsertPredicate]; !ok {
-			return fmt.Errorf("upsert predicate %q not found", upsertPredicate)
-		}
+	if uid, err := extractUIDFromDgraphQueryResult(resp.GetJson()); err != nil {
+		return nil, err
+	} else if uid != "" {
+		uids[uidField.String()] = uid
 	}
-
-	query := fmt.Sprintf(`{
-		q(func: eq(%s, "%s")) {
-			uid
+	walkUIDs(reflect.ValueOf(obj), func(uid reflect.Value) {
+		if assigned, ok := uids[strings.TrimPrefix(uid.String(), "_:")]; ok {
+			uid.SetString(assigned)
 		}
-	}`, upsertPredicate, upsertPredicates[upsertPredicate])
+	})
+	c.logger.V(2).Info("Upsert successful", "uid", uidField.String(), "uidCount", len(uids))
+	return resp.GetJson(), nil
+}
 
-	resp, err := c.QueryRaw(ctx, query, nil)
-	if err != nil {
-		return err
-	}
-	uid, err := extractUIDFromDgraphQueryResult(resp)
-	if err != nil {
-		return err
-	}
+// clearBlankUIDs resets the UIDs of obj and of the nodes it links to that name new
+// nodes, after the mutation creating them failed.
+func clearBlankUIDs(obj any) {
+	walkUIDs(reflect.ValueOf(obj), func(uid reflect.Value) {
+		if strings.HasPrefix(uid.String(), "_:") || strings.HasPrefix(uid.String(), "uid(") {
+			uid.SetString("")
+		}
+	})
+}
 
-	if uid == "" {
-		return c.Insert(ctx, obj)
+// walkUIDs calls fn with the UID field of v and of every node it links to.
+func walkUIDs(v reflect.Value, fn func(uid reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkUIDs(v.Elem(), fn)
+		}
+	case reflect.Slice, reflect.Array:
+		switch v.Type().Elem().Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Struct:
+			for i := 0; i < v.Len(); i++ {
+				walkUIDs(v.Index(i), fn)
+			}
+		}
+	case reflect.Struct:
+		uid := v.FieldByName("UID")
+		if !uid.IsValid() || uid.Kind() != reflect.String || !uid.CanSet() {
+			return
+		}
+		fn(uid)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				walkUIDs(v.Field(i), fn)
+			}
+		}
 	}
-	objValue := reflect.ValueOf(schemaObj)
-	objValue.Elem().FieldByName("UID").SetString(uid)
-	return c.Update(ctx, objValue.Interface())
 }
 
-func generateUniquePredicateQuery(predicates map[string]interface{}, nodeType string) (string, map[string]string) {
-	var queryBuf bytes.Buffer
-	vars := make(map[string]string)
-
-	// Build variable declarations and OR conditions
+// generateUpsertQuery returns a query for the node of type nodeType whose predicates
+// have all the given values, along with its variables. The UID of the node is held
+// by the variable upsertVar. Soft deleted nodes are skipped if excludeDeleted is set.
+func generateUpsertQuery(predicates map[string]any, nodeType string,
+	excludeDeleted bool) (string, map[string]string, error) {
+	vars := make(map[string]string, len(predicates))
 	varDecls := make([]string, 0, len(predicates))
 	conditions := make([]string, 0, len(predicates))
-	for key, val := range predicates {
-		varType := "string"
-		switch val.(type) {
-		case int, int32, int64, float32, float64:
-			varType = "int"
+	for i, key := range slices.Sorted(maps.Keys(predicates)) {
+		name := fmt.Sprintf("$p%d", i)
+		varType, value, err := dqlVar(predicates[key])
+		if err != nil {
+			return "", nil, fmt.Errorf("upsert predicate %q: %w", key, err)
 		}
-		varDecls = append(varDecls, fmt.Sprintf("$%s: %s", key, varType))
-		conditions = append(conditions, fmt.Sprintf("eq(%s, $%s)", key, key))
-		key = fmt.Sprintf("$%s", key)
-		vars[key] = fmt.Sprintf("%v", val)
+		varDecls = append(varDecls, fmt.Sprintf("%s: %s", name, varType))
+		conditions = append(conditions, fmt.Sprintf("eq(%s, %s)", key, name))
+		vars[name] = value
 	}
+	if excludeDeleted {
+		conditions = append(conditions, notDeletedFilter)
+	}
+
+	var queryBuf bytes.Buffer
 	queryBuf.WriteString("query q(")
 	queryBuf.WriteString(strings.Join(varDecls, ", "))
 	queryBuf.WriteString(") {\n")
-	queryBuf.WriteString(fmt.Sprintf("  q(func: type(%s)) @filter(%s) {\n", nodeType, strings.Join(conditions, " OR ")))
-	queryBuf.WriteString("    uid\n  }\n")
+	queryBuf.WriteString(fmt.Sprintf("  q(func: type(%s), first: 1) @filter(%s) {\n", nodeType,
+		strings.Join(conditions, " AND ")))
+	queryBuf.WriteString(fmt.Sprintf("    %s as uid\n  }\n", upsertVar))
 	queryBuf.WriteString("}\n")
 
-	return queryBuf.String(), vars
+	return queryBuf.String(), vars, nil
+}
+
+// dqlVar returns the DQL type and the value of a query variable holding val, or of the
+// value val points to. It fails if val is nil.
+func dqlVar(val any) (string, string, error) {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			break
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		return "", "", errors.New("value is nil")
+	}
+	if t, ok := rv.Interface().(time.Time); ok {
+		return "string", t.Format(time.RFC3339Nano), nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int", strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int", strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return "float", strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return "bool", strconv.FormatBool(rv.Bool()), nil
+	}
+	return "string", fmt.Sprintf("%v", rv.Interface()), nil
 }
 
 func getNodeType(obj any) string {
@@ -319,8 +451,76 @@ func getUpsertPredicates(obj any, firstOnly bool) map[string]any {
 	return getPredicatesByTag(obj, "upsert", firstOnly)
 }
 
-func getUniquePredicates(obj any) map[string]any {
-	return getPredicatesByTag(obj, "unique", false)
+// partialUpdateJSON returns the JSON of obj holding its UID and the given fields only,
+// named by predicate or by struct field name. Zero values are included.
+func partialUpdateJSON(obj any, fields []string) ([]byte, error) {
+	uid := getUIDValue(obj)
+	if uid == "" {
+		return nil, errors.New("object must have a UID to be updated")
+	}
+	// linked nodes that are created get their type
+	if err := dg.SetTypes(obj); err != nil {
+		return nil, err
+	}
+	data, err := resultJSON.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var encoded map[string]json.RawMessage
+	if err := resultJSON.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(obj).Elem()
+	result := map[string]any{"uid": uid}
+	visible := reflect.VisibleFields(v.Type())
+	for _, name := range fields {
+		index := slices.IndexFunc(visible, func(field reflect.StructField) bool {
+			return field.IsExported() && (field.Name == name || predicateName(field) == name)
+		})
+		if index == -1 {
+			return nil, fmt.Errorf("field %q not found in %s", name, v.Type().Name())
+		}
+		field := visible[index]
+		predicate := predicateName(field)
+		if predicate == "uid" || predicate == "dgraph.type" {
+			return nil, fmt.Errorf("field %q cannot be updated", name)
+		}
+		// fields omitted because they are empty are written with their zero value,
+		// which is also the value of a field promoted through a nil embedded pointer
+		if raw, ok := encoded[predicate]; ok {
+			result[predicate] = raw
+		} else if value, err := v.FieldByIndexErr(field.Index); err == nil {
+			result[predicate] = value.Interface()
+		} else {
+			result[predicate] = reflect.Zero(field.Type).Interface()
+		}
+	}
+	return resultJSON.Marshal(result)
+}
+
+// getPredicateValues returns the values of the given predicates of obj, keyed by predicate.
+func getPredicateValues(obj any, predicates []string) (map[string]any, error) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("object must be a struct")
+	}
+	values := make(map[string]any, len(predicates))
+	for i := 0; i < v.NumField(); i++ {
+		predName := predicateName(v.Type().Field(i))
+		if slices.Contains(predicates, predName) {
+			values[predName] = v.Field(i).Interface()
+		}
+	}
+	for _, predicate := range predicates {
+		if _, ok := values[predicate]; !ok {
+			return nil, fmt.Errorf("upsert predicate %q not found", predicate)
+		}
+	}
+	return values, nil
 }
 
 func getPredicatesByTag(obj any, tagName string, firstOnly bool) map[string]any {
@@ -340,31 +540,7 @@ func getPredicatesByTag(obj any, tagName string, firstOnly bool) map[string]any
 			continue
 		}
 
-		var predName string
-		if idx := strings.Index(tag, "predicate="); idx != -1 {
-			// Find the first comma or space after predicate=
-			endIdx := len(tag)
-			commaIdx := strings.Index(tag[idx:], ",")
-			spaceIdx := strings.Index(tag[idx:], " ")
-			if commaIdx != -1 && (spaceIdx == -1 || commaIdx < spaceIdx) {
-				endIdx = idx + commaIdx
-			} else if spaceIdx != -1 {
-				endIdx = idx + spaceIdx
-			}
-			predName = tag[idx+len("predicate=") : endIdx]
-		} else {
-			jsonTag := field.Tag.Get("json")
-			if jsonTag != "" && jsonTag != "-" {
-				commaIdx := strings.Index(jsonTag, ",")
-				if commaIdx != -1 {
-					predName = jsonTag[:commaIdx]
-				} else {
-					predName = jsonTag
-				}
-			} else {
-				predName = field.Name
-			}
-		}
+		predName := predicateName(field)
 		result[predName] = v.Field(i).Interface()
 		if firstOnly {
 			break
@@ -372,3 +548,29 @@ func getPredicatesByTag(obj any, tagName string, firstOnly bool) map[string]any
 	}
 	return result
 }
+
+// predicateName returns the name of the predicate a struct field is stored in, taken
+// from the `predicate=` option of its dgraph tag, its json tag or its name.
+func predicateName(field reflect.StructField) string {
+	tag := field.Tag.Get("dgraph")
+	if idx := strings.Index(tag, "predicate="); idx != -1 {
+		// Find the first comma or space after predicate=
+		endIdx := len(tag)
+		commaIdx := strings.Index(tag[idx:], ",")
+		spaceIdx := strings.Index(tag[idx:], " ")
+		if commaIdx != -1 && (spaceIdx == -1 || commaIdx < spaceIdx) {
+			endIdx = idx + commaIdx
+		} else if spaceIdx != -1 {
+			endIdx = idx + spaceIdx
+		}
+		return tag[idx+len("predicate=") : endIdx]
+	}
+	jsonTag := field.Tag.Get("json")
+	if jsonTag != "" && jsonTag != "-" {
+		if commaIdx := strings.Index(jsonTag, ","); commaIdx != -1 {
+			return jsonTag[:commaIdx]
+		}
+		return jsonTag
+	}
+	return field.Name
+}
@@ -21,9 +21,13 @@ func (ns *Namespace) ID() uint64 {
 	return ns.id
 }
 
-// DropAll drops all the data and schema in the modusDB instance.
+// DropAll drops all the data and schema in the namespace. As in Dgraph, only a drop
+// in the default namespace affects the whole modusDB instance.
 func (ns *Namespace) DropAll(ctx context.Context) error {
-	return ns.engine.DropAll(ctx)
+	if ns.ID() == 0 {
+		return ns.engine.DropAll(ctx)
+	}
+	return ns.engine.dropAllInNs(ctx, ns)
 }
 
 // DropData drops all the data in the modusDB instance.
@@ -31,6 +35,17 @@ func (ns *Namespace) DropData(ctx context.Context) error {
 	return ns.engine.dropData(ctx, ns)
 }
 
+// DropPredicate drops the predicate with all its data and indexes from the namespace.
+func (ns *Namespace) DropPredicate(ctx context.Context, pred string) error {
+	return ns.engine.dropPredicate(ctx, ns, pred)
+}
+
+// DropType drops the type definition from the namespace. The data of nodes of
+// the type is kept.
+func (ns *Namespace) DropType(ctx context.Context, typeName string) error {
+	return ns.engine.dropType(ctx, ns, typeName)
+}
+
 func (ns *Namespace) AlterSchema(ctx context.Context, sch string) error {
 	return ns.engine.alterSchema(ctx, ns, sch)
 }
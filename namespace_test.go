@@ -290,3 +290,86 @@ func TestDBDBRestart(t *testing.T) {
 	require.NoError(t, err)
 	require.JSONEq(t, `{"me":[{"bar":"B"}]}`, string(resp.GetJson()))
 }
+
+func TestDeleteNamespace(t *testing.T) {
+	dataDir := t.TempDir()
+	engine, err := modusgraph.NewEngine(modusgraph.NewDefaultConfig(dataDir))
+	require.NoError(t, err)
+	defer func() { engine.Close() }()
+
+	ns1, err := engine.CreateNamespace()
+	require.NoError(t, err)
+	ns2, err := engine.CreateNamespace()
+	require.NoError(t, err)
+
+	require.NoError(t, ns1.AlterSchema(context.Background(), "name: string @index(exact) ."))
+	_, err = ns1.Mutate(context.Background(), []*api.Mutation{
+		{
+			Set: []*api.NQuad{
+				{
+					Subject:     "_:aman",
+					Predicate:   "name",
+					ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: "A"}},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	namespaces, err := engine.ListNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, ns1.ID(), ns2.ID()}, namespaceIDs(namespaces))
+
+	require.ErrorIs(t, engine.DeleteNamespace(context.Background(), 0), modusgraph.ErrDeleteDefaultNamespace)
+	require.NoError(t, engine.DeleteNamespace(context.Background(), ns1.ID()))
+	require.ErrorIs(t, engine.DeleteNamespace(context.Background(), ns1.ID()), modusgraph.ErrNonExistentDB)
+
+	// the data and schema of the namespace are gone
+	resp, err := ns1.Query(context.Background(), `{ me(func: has(name)) { name } }`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"me":[]}`, string(resp.GetJson()))
+	resp, err = ns1.Query(context.Background(), `schema(pred: [name]) { type }`)
+	require.NoError(t, err)
+	require.JSONEq(t, `{}`, string(resp.GetJson()))
+
+	_, err = engine.GetNamespace(ns1.ID())
+	require.ErrorIs(t, err, modusgraph.ErrNonExistentDB)
+	namespaces, err = engine.ListNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, ns2.ID()}, namespaceIDs(namespaces))
+
+	// the deletion survives a restart and the ID is not reused
+	engine.Close()
+	engine, err = modusgraph.NewEngine(modusgraph.NewDefaultConfig(dataDir))
+	require.NoError(t, err)
+
+	_, err = engine.GetNamespace(ns1.ID())
+	require.ErrorIs(t, err, modusgraph.ErrNonExistentDB)
+	namespaces, err = engine.ListNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, ns2.ID()}, namespaceIDs(namespaces))
+
+	ns3, err := engine.CreateNamespace()
+	require.NoError(t, err)
+	require.Greater(t, ns3.ID(), ns2.ID())
+
+	// dropping all data keeps the namespaces and doesn't reuse IDs either
+	require.NoError(t, engine.DropAll(context.Background()))
+	_, err = engine.GetNamespace(ns1.ID())
+	require.ErrorIs(t, err, modusgraph.ErrNonExistentDB)
+	namespaces, err = engine.ListNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0, ns2.ID(), ns3.ID()}, namespaceIDs(namespaces))
+
+	ns4, err := engine.CreateNamespace()
+	require.NoError(t, err)
+	require.Greater(t, ns4.ID(), ns3.ID())
+}
+
+func namespaceIDs(namespaces []*modusgraph.Namespace) []uint64 {
+	ids := make([]uint64, 0, len(namespaces))
+	for _, ns := range namespaces {
+		ids = append(ids, ns.ID())
+	}
+	return ids
+}
@@ -0,0 +1,135 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"math"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+)
+
+// maxTxnAge is how long a transaction may stay open before the engine aborts it,
+// matching the timeout Dgraph applies to abandoned transactions.
+const maxTxnAge = 5 * time.Minute
+
+// oracle tracks the open transactions of the embedded engine and detects
+// write-write conflicts between them, in the same way Dgraph Zero does for a
+// cluster. It remembers the commit timestamp of every conflict key written while
+// other transactions were open, and a transaction conflicts if any of its keys
+// were committed after it started. A transaction that has only read so far does
+// not hold back the purge of remembered keys, so it conflicts when it starts
+// writing if any keys committed after it started may have been forgotten.
+//
+// The oracle is not safe for concurrent use, callers must hold the engine write lock.
+type oracle struct {
+	// open maps the start timestamp of every open transaction to its start time
+	open map[uint64]time.Time
+	// reading maps the start timestamp of every transaction that has not written yet
+	// to its start time
+	reading map[uint64]time.Time
+	// commits maps a conflict key to the latest commit timestamp that wrote it
+	commits map[string]uint64
+	// purgedTs is the latest commit timestamp whose keys may have been forgotten
+	purgedTs uint64
+}
+
+func newOracle() *oracle {
+	return &oracle{
+		open:    make(map[uint64]time.Time),
+		reading: make(map[uint64]time.Time),
+		commits: make(map[string]uint64),
+	}
+}
+
+// begin registers a new transaction starting at startTs.
+func (o *oracle) begin(startTs uint64) {
+	o.open[startTs] = time.Now()
+}
+
+// beginRead registers a new transaction starting at startTs that has not written yet.
+func (o *oracle) beginRead(startTs uint64) {
+	o.reading[startTs] = time.Now()
+}
+
+// isOpen reports whether the transaction starting at startTs is still open.
+func (o *oracle) isOpen(startTs uint64) bool {
+	_, ok := o.open[startTs]
+	if !ok {
+		_, ok = o.reading[startTs]
+	}
+	return ok
+}
+
+// write marks the transaction starting at startTs as writing, and reports whether
+// it is still open.
+func (o *oracle) write(startTs uint64) bool {
+	if started, ok := o.reading[startTs]; ok {
+		delete(o.reading, startTs)
+		o.open[startTs] = started
+	}
+	_, ok := o.open[startTs]
+	return ok
+}
+
+// done removes the transaction starting at startTs from the open set.
+func (o *oracle) done(startTs uint64) {
+	delete(o.open, startTs)
+	delete(o.reading, startTs)
+}
+
+// expired returns the start timestamps of transactions open for longer than maxTxnAge.
+func (o *oracle) expired() []uint64 {
+	var res []uint64
+	cutoff := time.Now().Add(-maxTxnAge)
+	for _, txns := range []map[uint64]time.Time{o.open, o.reading} {
+		for startTs, started := range txns {
+			if started.Before(cutoff) {
+				res = append(res, startTs)
+			}
+		}
+	}
+	return res
+}
+
+// hasConflict reports whether any of the keys written by the transaction
+// has been committed by another transaction after tc.StartTs.
+func (o *oracle) hasConflict(tc *api.TxnContext) bool {
+	if tc.StartTs < o.purgedTs {
+		return true
+	}
+	for _, key := range tc.Keys {
+		if commitTs, ok := o.commits[key]; ok && commitTs > tc.StartTs {
+			return true
+		}
+	}
+	return false
+}
+
+// commit records the keys of a transaction committed at commitTs. The transaction
+// must already be done. Keys only need to be remembered while a transaction that
+// started before commitTs is open, so older entries are purged.
+func (o *oracle) commit(tc *api.TxnContext, commitTs uint64) {
+	minOpen := uint64(math.MaxUint64)
+	for startTs := range o.open {
+		minOpen = min(minOpen, startTs)
+	}
+	for key, ts := range o.commits {
+		if ts < minOpen {
+			o.purgedTs = max(o.purgedTs, ts)
+			delete(o.commits, key)
+		}
+	}
+	if minOpen > commitTs {
+		if len(tc.Keys) > 0 {
+			o.purgedTs = max(o.purgedTs, commitTs)
+		}
+		return
+	}
+	for _, key := range tc.Keys {
+		o.commits[key] = commitTs
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	dg "github.com/dolan-in/dgman/v2"
+	"github.com/hypermodeinc/modusgraph"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,6 +63,75 @@ func TestClientSimpleGet(t *testing.T) {
 	}
 }
 
+func TestClientGetBy(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "GetByWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "GetByWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			entities := []*TestEntity{
+				{Name: "First Entity", Description: "The first entity"},
+				{Name: "Second Entity", Description: "The second entity"},
+			}
+			require.NoError(t, client.Insert(ctx, entities))
+			upserted := UpsertTestEntity{Name: "Upserted Entity", AnotherName: "Another Name"}
+			require.NoError(t, client.Insert(ctx, &upserted))
+
+			var entity TestEntity
+			require.NoError(t, client.GetBy(ctx, &entity, "name", "Second Entity"))
+			require.Equal(t, entities[1].UID, entity.UID)
+			require.Equal(t, "The second entity", entity.Description)
+
+			var got UpsertTestEntity
+			require.NoError(t, client.GetBy(ctx, &got, "anotherName", "Another Name"),
+				"Upsert predicates should be accepted")
+			require.Equal(t, upserted.UID, got.UID)
+
+			err := client.GetBy(ctx, &entity, "name", "Missing Entity")
+			var notFound *modusgraph.NotFoundError
+			require.ErrorAs(t, err, &notFound)
+			require.Equal(t, "TestEntity", notFound.NodeType)
+			require.Equal(t, "Missing Entity", notFound.Value)
+			require.ErrorIs(t, err, modusgraph.ErrNodeNotFound)
+
+			require.Error(t, client.GetBy(ctx, &entity, "description", "The first entity"),
+				"Predicates that are not unique should be rejected")
+			require.Error(t, client.GetBy(ctx, entity, "name", "First Entity"), "Objects must be pointers")
+
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err)
+			defer func() { _ = tx.Discard(ctx) }()
+			require.NoError(t, tx.Insert(ctx, &TestEntity{Name: "Third Entity"}))
+			require.NoError(t, tx.GetBy(ctx, &entity, "name", "Third Entity"),
+				"The transaction should see its own writes")
+			require.ErrorIs(t, client.GetBy(ctx, &entity, "name", "Third Entity"), modusgraph.ErrNodeNotFound)
+		})
+	}
+}
+
 type GeoLocation struct {
 	Type  string    `json:"type"`
 	Coord []float64 `json:"coordinates"`
@@ -0,0 +1,252 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+	"github.com/go-logr/logr"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func init() {
+	jsoniter.RegisterExtension(&vectorExtension{})
+}
+
+// vectorTag holds the options of a vector predicate declared in a dgraph struct tag,
+// for example `dgraph:"vector,index=hnsw,metric=cosine,exponent=4"`. Options can be
+// separated by commas or spaces.
+type vectorTag struct {
+	predicate string
+	hnsw      bool
+	metric    string
+	exponent  string
+}
+
+// parseVectorTag parses a dgraph struct tag, reporting whether it declares a vector predicate.
+func parseVectorTag(tag string) (vectorTag, bool, error) {
+	var vt vectorTag
+	var isVector bool
+	var index string
+	fields := strings.FieldsFunc(tag, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, field := range fields {
+		key, value, _ := strings.Cut(field, "=")
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "vector":
+			isVector = true
+		case "index":
+			index = value
+		case "metric":
+			vt.metric = value
+		case "exponent":
+			vt.exponent = value
+		case "predicate":
+			vt.predicate = value
+		}
+	}
+	if !isVector {
+		return vt, false, nil
+	}
+
+	switch index {
+	case "":
+	case "hnsw":
+		vt.hnsw = true
+	default:
+		return vt, false, fmt.Errorf("unsupported vector index %q", index)
+	}
+	switch vt.metric {
+	case "", metricEuclidean, metricCosine, metricDotProduct:
+	default:
+		return vt, false, fmt.Errorf("unsupported distance metric %q", vt.metric)
+	}
+	if vt.exponent != "" {
+		if _, err := strconv.ParseUint(vt.exponent, 10, 32); err != nil {
+			return vt, false, fmt.Errorf("invalid hnsw exponent %q", vt.exponent)
+		}
+	}
+	return vt, true, nil
+}
+
+// schema returns the Dgraph schema of the vector predicate.
+func (vt vectorTag) schema(predicate string) *dg.Schema {
+	s := &dg.Schema{Predicate: predicate, Type: "float32vector"}
+	if !vt.hnsw {
+		return s
+	}
+	var params []string
+	if vt.metric != "" {
+		params = append(params, fmt.Sprintf("metric: %q", vt.metric))
+	}
+	if vt.exponent != "" {
+		params = append(params, fmt.Sprintf("exponent: %q", vt.exponent))
+	}
+	// dgman writes the tokenizers of predicates without an index flag as is, which is
+	// how Dgraph reports hnsw indexes
+	s.Tokenizer = []string{"hnsw(" + strings.Join(params, ", ") + ")"}
+	return s
+}
+
+// createSchema generates the types and predicates of the models in the same way as
+// dg.CreateSchema, and alters the schema with the predicates that do not exist yet.
+// Unlike dg.CreateSchema, []float32 fields tagged as vectors are declared as
+// float32vector predicates, with their hnsw index.
+func createSchema(ctx context.Context, client *dgo.Dgraph, logger logr.Logger, models ...any) error {
+	typeSchema := dg.NewTypeSchema()
+	typeSchema.Marshal("", models...)
+
+	for _, model := range models {
+		modelType := reflect.TypeOf(model)
+		for modelType.Kind() == reflect.Ptr || modelType.Kind() == reflect.Slice {
+			modelType = modelType.Elem()
+		}
+		hasDType := false
+		for i := range modelType.NumField() {
+			if strings.Split(modelType.Field(i).Tag.Get("json"), ",")[0] == "dgraph.type" {
+				hasDType = true
+				break
+			}
+		}
+		if !hasDType {
+			return fmt.Errorf("missing required field DType []string `json:\"dgraph.type\"` in type %s",
+				modelType.Name())
+		}
+		if err := applyVectorSchema(typeSchema, modelType, make(map[reflect.Type]bool)); err != nil {
+			return err
+		}
+	}
+
+	resp, err := client.NewReadOnlyTxn().Query(ctx, `schema {}`)
+	if err != nil {
+		return err
+	}
+	var existing struct {
+		Schema []*dg.Schema `json:"schema"`
+	}
+	if err := json.Unmarshal(resp.Json, &existing); err != nil {
+		return err
+	}
+	for _, s := range existing.Schema {
+		if want, ok := typeSchema.Schema[s.Predicate]; ok {
+			if want.String() != s.String() {
+				logger.V(1).Info("Schema conflict, keeping existing predicate", "predicate", s.Predicate,
+					"existing", s.String(), "new", want.String())
+			}
+			delete(typeSchema.Schema, s.Predicate)
+		}
+	}
+
+	return client.Alter(ctx, &api.Operation{Schema: typeSchema.String()})
+}
+
+// applyVectorSchema replaces the schema dgman generated for the vector fields of
+// modelType, and of the types it has edges to.
+func applyVectorSchema(typeSchema *dg.TypeSchema, modelType reflect.Type, seen map[reflect.Type]bool) error {
+	if modelType.Kind() != reflect.Struct || seen[modelType] {
+		return nil
+	}
+	seen[modelType] = true
+
+	nodeType := dg.GetNodeType(reflect.New(modelType).Interface())
+	for i := range modelType.NumField() {
+		field := modelType.Field(i)
+		vt, ok, err := parseVectorTag(field.Tag.Get("dgraph"))
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %w", modelType.Name(), field.Name, err)
+		}
+		if !ok {
+			elemType := field.Type
+			for elemType.Kind() == reflect.Ptr || elemType.Kind() == reflect.Slice {
+				elemType = elemType.Elem()
+			}
+			if err := applyVectorSchema(typeSchema, elemType, seen); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Type != reflect.TypeOf([]float32(nil)) {
+			return fmt.Errorf("field %s.%s: vector fields must be of type []float32", modelType.Name(), field.Name)
+		}
+		predicate := vt.predicate
+		if predicate == "" {
+			predicate = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+		s := vt.schema(predicate)
+		typeSchema.Schema[predicate] = s
+		if predicates, ok := typeSchema.Types[nodeType]; ok {
+			predicates[predicate] = s
+		}
+	}
+	return nil
+}
+
+// vectorExtension encodes []float32 fields tagged as vectors in the format Dgraph
+// expects for float32vector values in JSON mutations, a string such as "[0.1,0.2]".
+// The extension applies to dgman, which encodes and decodes objects with jsoniter.
+type vectorExtension struct {
+	jsoniter.DummyExtension
+}
+
+func (e *vectorExtension) UpdateStructDescriptor(desc *jsoniter.StructDescriptor) {
+	for _, binding := range desc.Fields {
+		if binding.Field.Type().Type1() != reflect.TypeOf([]float32(nil)) {
+			continue
+		}
+		tag, _ := binding.Field.Tag().Lookup("dgraph")
+		if _, ok, _ := parseVectorTag(tag); !ok {
+			continue
+		}
+		binding.Encoder = &vectorEncoder{}
+		binding.Decoder = &vectorDecoder{}
+	}
+}
+
+type vectorEncoder struct{}
+
+func (e *vectorEncoder) IsEmpty(ptr unsafe.Pointer) bool {
+	return len(*(*[]float32)(ptr)) == 0
+}
+
+func (e *vectorEncoder) Encode(ptr unsafe.Pointer, stream *jsoniter.Stream) {
+	vec := *(*[]float32)(ptr)
+	if vec == nil {
+		stream.WriteNil()
+		return
+	}
+	stream.WriteString(formatVector(vec))
+}
+
+// vectorDecoder decodes vectors returned as a JSON array by queries, or as a string.
+type vectorDecoder struct{}
+
+func (d *vectorDecoder) Decode(ptr unsafe.Pointer, iter *jsoniter.Iterator) {
+	vec := (*[]float32)(ptr)
+	switch iter.WhatIsNext() {
+	case jsoniter.NilValue:
+		iter.ReadNil()
+		*vec = nil
+	case jsoniter.StringValue:
+		if err := json.Unmarshal([]byte(iter.ReadString()), vec); err != nil {
+			iter.ReportError("decode vector", err.Error())
+		}
+	default:
+		*vec = (*vec)[:0]
+		iter.ReadArrayCB(func(iter *jsoniter.Iterator) bool {
+			*vec = append(*vec, iter.ReadFloat32())
+			return true
+		})
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	metricEuclidean  = "euclidean"
+	metricCosine     = "cosine"
+	metricDotProduct = "dotproduct"
+
+	// similarBlock is the name of the query block returning the results of SimilarTo
+	similarBlock = "similar"
+	// distanceAlias is the name under which SimilarTo returns the distance of each result
+	distanceAlias = "_distance"
+)
+
+// resultJSON encodes and decodes objects with the configuration dgman uses, so that the
+// types it registers, such as dg.VectorFloat32, are handled the same way as with Get,
+// Query and mutations.
+var resultJSON = jsoniter.Config{
+	EscapeHTML:             true,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+}.Froze()
+
+// SimilarTo implements vector similarity search on a float32vector predicate.
+func (c client) SimilarTo(ctx context.Context, results any, predicate string, vec []float32, k int,
+	opts ...QueryOpt) ([]float64, error) {
+
+	var distances []float64
+	err := c.intercept(ctx, "SimilarTo", results, func(ctx context.Context) error {
+		var err error
+		distances, err = c.similarResults(ctx, results, predicate, vec, k, opts)
+		return err
+	})
+	return distances, err
+}
+
+// similarResults runs SimilarTo, decoding the nearest nodes into results.
+func (c client) similarResults(ctx context.Context, results any, predicate string, vec []float32, k int,
+	opts []QueryOpt) ([]float64, error) {
+
+	options := newQueryOptions(c, opts)
+	resp, err := c.similarTo(ctx, predicate, vec, k, options, true)
+	if err != nil {
+		return nil, err
+	}
+	hits := resp[similarBlock]
+	if err := resultJSON.Unmarshal(hits, results); err != nil {
+		return nil, err
+	}
+	var distances []struct {
+		Distance float64 `json:"_distance"`
+	}
+	if err := resultJSON.Unmarshal(hits, &distances); err != nil {
+		return nil, err
+	}
+	res := make([]float64, len(distances))
+	for i, d := range distances {
+		res[i] = d.Distance
+	}
+	return res, nil
+}
+
+// similarTo runs a similarity query for the k nodes nearest to vec and returns the
+// blocks of the response. The similarBlock block holds the nearest nodes, ordered
+// from the most to the least similar, with their distance under distanceAlias. If
+// paginate is false, first and offset are left for the caller to apply. A block
+// listing the uids of the nearest nodes matching each text boost is added, named
+// after the boost.
+func (c client) similarTo(ctx context.Context, predicate string, vec []float32, k int,
+	options queryOptions, paginate bool) (map[string]stdjson.RawMessage, error) {
+
+	if k <= 0 {
+		return nil, errors.New("k must be greater than zero")
+	}
+	if len(vec) == 0 {
+		return nil, errors.New("query vector cannot be empty")
+	}
+	distance, order, err := distanceExpr(options.metric, vec)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := maps.Clone(options.vars)
+	if vars == nil {
+		vars = make(map[string]string, 1+len(options.boosts))
+	}
+	vars["$vector"] = formatVector(vec)
+	typed := []string{"$vector: float32vector"}
+	for i, boost := range options.boosts {
+		name := boost.name(i)
+		vars["$"+name] = boost.text
+		typed = append(typed, "$"+name+": string")
+	}
+
+	var q strings.Builder
+	q.WriteString("query ")
+	q.WriteString(varsDefinition("similar_to", options.vars, typed...))
+	fmt.Fprintf(&q, " {\n\tvar(func: similar_to(%s, %d, $vector)) {\n", predicate, k)
+	fmt.Fprintf(&q, "\t\tvector as %s\n\t\tdistance as math(%s)\n\t}\n", predicate, distance)
+	fmt.Fprintf(&q, "\t%s(func: uid(distance), %s: val(distance)", similarBlock, order)
+	if !paginate {
+		options.first, options.offset = 0, 0
+	}
+	writeQueryArgs(&q, options)
+	q.WriteString(") ")
+	writeQueryFilter(&q, options.filter)
+	q.WriteString("{\n")
+	writeExpandAll(&q, options.depth, 2)
+	fmt.Fprintf(&q, "\t\t%s: val(distance)\n\t}\n", distanceAlias)
+	for i, boost := range options.boosts {
+		name := boost.name(i)
+		fmt.Fprintf(&q, "\t%s(func: uid(distance)) @filter(%s(%s, $%s)) {\n\t\tuid\n\t}\n",
+			name, boost.function, boost.predicate, name)
+	}
+	q.WriteString("}")
+
+	c.logger.V(2).Info("Executing similarity query", "predicate", predicate, "k", k,
+		"boosts", len(options.boosts))
+	data, err := c.queryRaw(ctx, nil, q.String(), vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp map[string]stdjson.RawMessage
+	if err := resultJSON.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if _, ok := resp[similarBlock]; !ok {
+		resp[similarBlock] = stdjson.RawMessage("[]")
+	}
+	return resp, nil
+}
+
+// distanceExpr returns the DQL math expression computing the distance between the
+// `vector` value variable and the `$vector` query variable under metric, and the
+// order that puts the most similar nodes first. The norm of vec is computed here
+// since Dgraph cannot evaluate a math expression made only of constants.
+func distanceExpr(metric string, vec []float32) (expr string, order string, err error) {
+	switch metric {
+	case metricEuclidean:
+		return "sqrt((vector - $vector) dot (vector - $vector))", "orderasc", nil
+	case metricCosine:
+		var norm float64
+		for _, v := range vec {
+			norm += float64(v) * float64(v)
+		}
+		if norm == 0 {
+			return "", "", errors.New("cosine distance is undefined for a zero query vector")
+		}
+		norm = math.Sqrt(norm)
+		return fmt.Sprintf("1.0 - (vector dot $vector) / (sqrt(vector dot vector) * %s)",
+			strconv.FormatFloat(norm, 'f', -1, 64)), "orderasc", nil
+	case metricDotProduct:
+		return "vector dot $vector", "orderdesc", nil
+	default:
+		return "", "", fmt.Errorf("unsupported distance metric %q", metric)
+	}
+}
+
+// formatVector formats vec as a float32vector value, for example `[0.1, 0.2]`.
+func formatVector(vec []float32) string {
+	values := make([]string, len(vec))
+	for i, v := range vec {
+		values[i] = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// writeQueryArgs writes the ordering and pagination arguments of a root function.
+func writeQueryArgs(q *strings.Builder, options queryOptions) {
+	for _, order := range options.orders {
+		if order.desc {
+			fmt.Fprintf(q, ", orderdesc: %s", order.predicate)
+		} else {
+			fmt.Fprintf(q, ", orderasc: %s", order.predicate)
+		}
+	}
+	if options.first > 0 {
+		fmt.Fprintf(q, ", first: %d", options.first)
+	}
+	if options.offset > 0 {
+		fmt.Fprintf(q, ", offset: %d", options.offset)
+	}
+}
+
+// writeQueryFilter writes the filter of a query block. Nodes without a type are
+// always filtered out, as they are left behind by deletes.
+func writeQueryFilter(q *strings.Builder, filter string) {
+	if filter == "" {
+		q.WriteString("@filter(has(dgraph.type)) ")
+		return
+	}
+	fmt.Fprintf(q, "@filter(has(dgraph.type) AND (%s)) ", filter)
+}
+
+// writeExpandAll writes the selection of all predicates of a node, following edges
+// up to depth levels, in the same way as Query does.
+func writeExpandAll(q *strings.Builder, depth, indent int) {
+	tabs := strings.Repeat("\t", indent)
+	fmt.Fprintf(q, "%suid\n%sdgraph.type\n%sexpand(_all_)", tabs, tabs, tabs)
+	if depth > 0 {
+		q.WriteString(" {\n")
+		writeExpandAll(q, depth-1, indent+1)
+		fmt.Fprintf(q, "%s}", tabs)
+	}
+	q.WriteString("\n")
+}
@@ -0,0 +1,188 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// SoftDeletePredicate is the predicate holding the time a node was deleted at, when
+// the client is created with WithSoftDelete.
+const SoftDeletePredicate = "deletedAt"
+
+// notDeletedFilter is the DQL filter matching nodes that are not soft deleted
+const notDeletedFilter = "NOT has(" + SoftDeletePredicate + ")"
+
+// softDeleteSchema indexes the soft delete marker, so that Purge can find old deletions
+const softDeleteSchema = SoftDeletePredicate + ": datetime @index(hour) ."
+
+// liveFilter returns filter restricted to nodes that are not soft deleted, if the client
+// soft deletes nodes.
+func (c client) liveFilter(filter string) string {
+	switch {
+	case !c.options.softDelete:
+		return filter
+	case filter == "":
+		return notDeletedFilter
+	default:
+		return "(" + filter + ") AND " + notDeletedFilter
+	}
+}
+
+// excludeDeleted filters soft deleted nodes out of q, if the client soft deletes nodes.
+// Filter replaces the filter of q, so filters set on q afterwards are built with liveFilter.
+func (c client) excludeDeleted(q *dg.Query) *dg.Query {
+	if c.options.softDelete {
+		q.Filter(notDeletedFilter)
+	}
+	return q
+}
+
+// ensureSoftDeleteSchema makes sure the soft delete marker is an indexed datetime. The
+// embedded engine applies it when the client is created, while remote Dgraph gets it
+// on first use, as NewClient doesn't connect to it.
+func (c client) ensureSoftDeleteSchema(ctx context.Context) error {
+	if c.softDeleteApplied.Load() {
+		return nil
+	}
+	client, err := c.pool.get()
+	if err != nil {
+		c.logger.Error(err, "Failed to get client from pool")
+		return err
+	}
+	defer c.pool.put(client)
+
+	if err := client.Alter(ctx, &api.Operation{Schema: softDeleteSchema}); err != nil {
+		return err
+	}
+	c.softDeleteApplied.Store(true)
+	return nil
+}
+
+// softDelete marks the nodes with the given UIDs, and the nodes they own if the options
+// ask for it, as deleted at the current time.
+func (c client) softDelete(ctx context.Context, tx *dg.TxnContext, uids []string, options deleteOptions) error {
+	if len(uids) == 0 {
+		return errors.New("uids cannot be empty")
+	}
+	if err := c.ensureSoftDeleteSchema(ctx); err != nil {
+		return err
+	}
+	deletedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	return c.withTxn(ctx, tx, func(txn *dg.TxnContext) error {
+		deleted := uids
+		if len(options.cascade) > 0 {
+			var err error
+			if deleted, err = cascadeUIDs(ctx, txn, uids, options.cascade); err != nil {
+				return err
+			}
+		}
+		nquads := make([]*api.NQuad, len(deleted))
+		for i, uid := range deleted {
+			nquads[i] = &api.NQuad{
+				Subject:     uid,
+				Predicate:   SoftDeletePredicate,
+				ObjectValue: &api.Value{Val: &api.Value_StrVal{StrVal: deletedAt}},
+			}
+		}
+		c.logger.V(2).Info("Soft deleting nodes", "uidCount", len(deleted))
+		_, err := txn.Txn().Mutate(ctx, &api.Mutation{Set: nquads, CommitNow: tx == nil})
+		return err
+	})
+}
+
+// Restore implements undoing the soft delete of objects.
+func (c client) Restore(ctx context.Context, uids []string, opts ...DeleteOpt) error {
+	return c.intercept(ctx, "Restore", uids, func(ctx context.Context) error {
+		return c.restore(ctx, uids, opts)
+	})
+}
+
+func (c client) restore(ctx context.Context, uids []string, opts []DeleteOpt) error {
+	if len(uids) == 0 {
+		return errors.New("uids cannot be empty")
+	}
+	var options deleteOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if err := c.ensureSoftDeleteSchema(ctx); err != nil {
+		return err
+	}
+	return c.withTxn(ctx, nil, func(txn *dg.TxnContext) error {
+		restored := uids
+		if len(options.cascade) > 0 {
+			var err error
+			if restored, err = cascadeUIDs(ctx, txn, uids, options.cascade); err != nil {
+				return err
+			}
+		}
+		nquads := make([]*api.NQuad, len(restored))
+		for i, uid := range restored {
+			nquads[i] = &api.NQuad{
+				Subject:     uid,
+				Predicate:   SoftDeletePredicate,
+				ObjectValue: &api.Value{Val: &api.Value_DefaultVal{DefaultVal: deleteAllValues}},
+			}
+		}
+		c.logger.V(2).Info("Restoring nodes", "uidCount", len(restored))
+		_, err := txn.Txn().Mutate(ctx, &api.Mutation{Del: nquads, CommitNow: true})
+		return err
+	})
+}
+
+// Purge implements permanently removing objects soft deleted before the retention period.
+func (c client) Purge(ctx context.Context, olderThan time.Duration) (int, error) {
+	var purged int
+	err := c.intercept(ctx, "Purge", olderThan, func(ctx context.Context) error {
+		var err error
+		purged, err = c.purge(ctx, olderThan)
+		return err
+	})
+	return purged, err
+}
+
+func (c client) purge(ctx context.Context, olderThan time.Duration) (int, error) {
+	if err := c.ensureSoftDeleteSchema(ctx); err != nil {
+		return 0, err
+	}
+	before := time.Now().Add(-olderThan).UTC().Format(time.RFC3339Nano)
+	var purged int
+	err := c.withTxn(ctx, nil, func(txn *dg.TxnContext) error {
+		q := `query purge($before: string) {
+  nodes(func: le(` + SoftDeletePredicate + `, $before)) { uid }
+}`
+		resp, err := txn.Txn().QueryWithVars(ctx, q, map[string]string{"$before": before})
+		if err != nil {
+			return err
+		}
+		var data struct {
+			Nodes []uidNode `json:"nodes"`
+		}
+		if err := json.Unmarshal(resp.Json, &data); err != nil {
+			return err
+		}
+		if len(data.Nodes) == 0 {
+			return txn.Discard()
+		}
+		uids := make([]string, len(data.Nodes))
+		for i, node := range data.Nodes {
+			uids[i] = node.UID
+		}
+		purged = len(uids)
+		return c.deleteNodes(ctx, txn, true, uids, deleteOptions{inbound: true, purge: true})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
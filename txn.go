@@ -0,0 +1,233 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dgraph-io/dgo/v250"
+	dg "github.com/dolan-in/dgman/v2"
+)
+
+// Txn is an explicit transaction that groups several operations into one atomic unit.
+// Reads performed through a Txn observe its own uncommitted writes. A Txn is obtained
+// from Client.Begin and must be finished with either Commit or Discard.
+type Txn interface {
+	// Insert adds a new object or slice of objects within the transaction.
+	Insert(context.Context, any) error
+
+	// Upsert inserts an object if it doesn't exist or updates it if it does,
+	// within the transaction. See Client.Upsert for predicate selection.
+	Upsert(context.Context, any, ...string) error
+
+	// Update modifies an existing object within the transaction.
+	Update(context.Context, any) error
+
+	// UpdateFields writes only the named fields of an existing object within the transaction.
+	UpdateFields(context.Context, any, ...string) error
+
+	// Unset removes all values of the predicates from a node within the transaction.
+	Unset(context.Context, string, ...string) error
+
+	// RemoveEdges removes edges of a predicate from a node within the transaction.
+	RemoveEdges(context.Context, string, string, ...string) error
+
+	// Delete removes objects with the specified UIDs within the transaction.
+	// See Client.Delete for the options.
+	Delete(context.Context, []string, ...DeleteOpt) error
+
+	// Get retrieves a single object by its UID as seen by the transaction.
+	Get(context.Context, any, string) error
+
+	// GetBy retrieves a single object by the value of a unique predicate as seen by
+	// the transaction. See Client.GetBy.
+	GetBy(context.Context, any, string, any) error
+
+	// Query creates a new query builder that reads through the transaction.
+	Query(context.Context, any) *dg.Query
+
+	// QueryRaw executes a raw Dgraph query with optional query variables
+	// as seen by the transaction.
+	QueryRaw(context.Context, string, map[string]string) ([]byte, error)
+
+	// Commit applies all operations of the transaction atomically. If another
+	// transaction committed a conflicting write first, dgo.ErrAborted is returned.
+	Commit(context.Context) error
+
+	// Discard rolls back the transaction. It is safe to call after Commit,
+	// in which case it is a no-op, so it can be deferred right after Begin.
+	Discard(context.Context) error
+}
+
+type txn struct {
+	mutex    sync.Mutex
+	client   client
+	dgo      *dgo.Dgraph
+	tx       *dg.TxnContext
+	finished bool
+}
+
+// run executes fn while holding the transaction lock, after making sure the
+// transaction is still active and bound to the caller's context.
+func (t *txn) run(ctx context.Context, fn func() error) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.finished {
+		return dgo.ErrFinished
+	}
+	t.tx.WithContext(ctx)
+	return fn()
+}
+
+// Insert implements inserting an object or slice of objects in the transaction.
+func (t *txn) Insert(ctx context.Context, obj any) error {
+	return t.client.intercept(ctx, "Insert", obj, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.insert(ctx, t.tx, obj)
+		})
+	})
+}
+
+// Upsert implements inserting or updating an object or slice of objects in the transaction.
+func (t *txn) Upsert(ctx context.Context, obj any, predicates ...string) error {
+	return t.client.intercept(ctx, "Upsert", obj, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.upsertWithPredicates(ctx, t.tx, obj, predicates...)
+		})
+	})
+}
+
+// Update implements updating an existing object in the transaction.
+func (t *txn) Update(ctx context.Context, obj any) error {
+	return t.client.intercept(ctx, "Update", obj, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.update(ctx, t.tx, obj)
+		})
+	})
+}
+
+// UpdateFields implements updating the named fields of an object in the transaction.
+func (t *txn) UpdateFields(ctx context.Context, obj any, fields ...string) error {
+	return t.client.intercept(ctx, "UpdateFields", obj, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.updateFields(ctx, t.tx, obj, fields)
+		})
+	})
+}
+
+// Unset implements removing all values of predicates from a node in the transaction.
+func (t *txn) Unset(ctx context.Context, uid string, predicates ...string) error {
+	return t.client.intercept(ctx, "Unset", uid, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.unset(ctx, t.tx, uid, predicates)
+		})
+	})
+}
+
+// RemoveEdges implements removing edges of a predicate from a node in the transaction.
+func (t *txn) RemoveEdges(ctx context.Context, uid string, predicate string, targetUIDs ...string) error {
+	return t.client.intercept(ctx, "RemoveEdges", uid, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.removeEdges(ctx, t.tx, uid, predicate, targetUIDs)
+		})
+	})
+}
+
+// Delete implements removing objects with the specified UIDs in the transaction.
+func (t *txn) Delete(ctx context.Context, uids []string, opts ...DeleteOpt) error {
+	return t.client.intercept(ctx, "Delete", uids, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.delete(ctx, t.tx, uids, opts)
+		})
+	})
+}
+
+// Get implements retrieving a single object by its UID in the transaction.
+func (t *txn) Get(ctx context.Context, obj any, uid string) error {
+	return t.client.intercept(ctx, "Get", obj, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.get(ctx, t.tx, obj, uid)
+		})
+	})
+}
+
+// GetBy implements retrieving a single object by the value of a unique predicate in
+// the transaction.
+func (t *txn) GetBy(ctx context.Context, obj any, predicate string, value any) error {
+	return t.client.intercept(ctx, "GetBy", obj, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			return t.client.getBy(t.tx, obj, predicate, value)
+		})
+	})
+}
+
+// Query returns a *dg.Query bound to the transaction. It returns nil if the
+// transaction has already been committed or discarded.
+func (t *txn) Query(ctx context.Context, model any) *dg.Query {
+	var q *dg.Query
+	_ = t.run(ctx, func() error {
+		q = t.client.excludeDeleted(t.tx.Get(model).All(t.client.options.maxEdgeTraversal))
+		return nil
+	})
+	return q
+}
+
+// QueryRaw implements raw querying (DQL syntax) in the transaction.
+func (t *txn) QueryRaw(ctx context.Context, q string, vars map[string]string) ([]byte, error) {
+	var resp []byte
+	err := t.client.intercept(ctx, "QueryRaw", q, func(ctx context.Context) error {
+		return t.run(ctx, func() error {
+			var err error
+			resp, err = t.client.queryRaw(ctx, t.tx, q, vars)
+			return err
+		})
+	})
+	return resp, err
+}
+
+// Commit implements committing the transaction.
+func (t *txn) Commit(ctx context.Context) error {
+	return t.client.intercept(ctx, "Commit", nil, func(ctx context.Context) error {
+		return t.finish(ctx, true)
+	})
+}
+
+// Discard implements rolling back the transaction.
+func (t *txn) Discard(ctx context.Context) error {
+	// discarding a finished transaction is a no-op, which is not worth intercepting
+	t.mutex.Lock()
+	finished := t.finished
+	t.mutex.Unlock()
+	if finished {
+		return nil
+	}
+	return t.client.intercept(ctx, "Discard", nil, func(ctx context.Context) error {
+		return t.finish(ctx, false)
+	})
+}
+
+func (t *txn) finish(ctx context.Context, commit bool) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.finished {
+		if commit {
+			return dgo.ErrFinished
+		}
+		return nil
+	}
+	t.finished = true
+	defer t.client.pool.put(t.dgo)
+
+	if commit {
+		t.client.logger.V(2).Info("Committing transaction")
+		return t.tx.Txn().Commit(ctx)
+	}
+	t.client.logger.V(2).Info("Discarding transaction")
+	return t.tx.Txn().Discard(ctx)
+}
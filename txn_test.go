@@ -0,0 +1,198 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/dgo/v250"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientTxn(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "TxnWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "TxnWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, client.UpdateSchema(ctx, TestEntity{}))
+
+			existing := TestEntity{
+				Name:        "Existing Entity",
+				Description: "Will be deleted in the transaction",
+				CreatedAt:   time.Now(),
+			}
+			require.NoError(t, client.Insert(ctx, &existing))
+
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err, "Begin should succeed")
+			defer func() {
+				require.NoError(t, tx.Discard(ctx), "Discard after commit should be a no-op")
+			}()
+
+			first := TestEntity{
+				Name:        "First Entity",
+				Description: "First entity in the transaction",
+				CreatedAt:   time.Now(),
+			}
+			second := TestEntity{
+				Name:        "Second Entity",
+				Description: "Second entity in the transaction",
+				CreatedAt:   time.Now(),
+			}
+			require.NoError(t, tx.Insert(ctx, &first), "Insert should succeed")
+			require.NoError(t, tx.Insert(ctx, &second), "Insert should succeed")
+			require.NotEmpty(t, first.UID, "UID should be assigned")
+			require.NotEmpty(t, second.UID, "UID should be assigned")
+
+			first.Description = "First entity, updated"
+			require.NoError(t, tx.Update(ctx, &first), "Update should succeed")
+			second.Description = "Second entity, updated"
+			require.NoError(t, tx.Update(ctx, &second), "Update should succeed")
+			require.NoError(t, tx.Delete(ctx, []string{existing.UID}), "Delete should succeed")
+
+			var inTxn TestEntity
+			require.NoError(t, tx.Get(ctx, &inTxn, first.UID), "Get in transaction should succeed")
+			require.Equal(t, "First entity, updated", inTxn.Description, "Transaction should see its own writes")
+
+			require.NoError(t, tx.Commit(ctx), "Commit should succeed")
+			require.ErrorIs(t, tx.Commit(ctx), dgo.ErrFinished, "Second commit should fail")
+			require.ErrorIs(t, tx.Insert(ctx, &TestEntity{Name: "Late"}), dgo.ErrFinished,
+				"Operations after commit should fail")
+
+			var entities []TestEntity
+			err = client.Query(ctx, TestEntity{}).OrderAsc("name").Nodes(&entities)
+			require.NoError(t, err, "Query should succeed")
+			require.Len(t, entities, 2, "Only the entities inserted in the transaction should remain")
+			require.Equal(t, "First entity, updated", entities[0].Description)
+			require.Equal(t, "Second entity, updated", entities[1].Description)
+		})
+	}
+}
+
+func TestClientTxnIsolation(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "TxnIsolationWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "TxnIsolationWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, client.UpdateSchema(ctx, TestEntity{}))
+
+			entity := TestEntity{
+				Name:        "Shared Entity",
+				Description: "Original description",
+				CreatedAt:   time.Now(),
+			}
+			require.NoError(t, client.Insert(ctx, &entity))
+
+			// Uncommitted writes are invisible outside the transaction and rolled back by Discard
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err, "Begin should succeed")
+			pending := TestEntity{
+				Name:        "Pending Entity",
+				Description: "Never committed",
+				CreatedAt:   time.Now(),
+			}
+			require.NoError(t, tx.Insert(ctx, &pending), "Insert should succeed")
+
+			var entities []TestEntity
+			require.NoError(t, client.Query(ctx, TestEntity{}).Nodes(&entities))
+			require.Len(t, entities, 1, "Pending insert should not be visible outside the transaction")
+
+			require.NoError(t, tx.Discard(ctx), "Discard should succeed")
+			require.NoError(t, client.Query(ctx, TestEntity{}).Nodes(&entities))
+			require.Len(t, entities, 1, "Discarded insert should be rolled back")
+
+			// Concurrent transactions writing the same node conflict, the later commit aborts
+			tx1, err := client.Begin(ctx)
+			require.NoError(t, err, "Begin should succeed")
+			defer func() { _ = tx1.Discard(ctx) }()
+			tx2, err := client.Begin(ctx)
+			require.NoError(t, err, "Begin should succeed")
+			defer func() { _ = tx2.Discard(ctx) }()
+
+			first := entity
+			first.Description = "Updated by the first transaction"
+			require.NoError(t, tx1.Update(ctx, &first), "Update should succeed")
+			second := entity
+			second.Description = "Updated by the second transaction"
+			require.NoError(t, tx2.Update(ctx, &second), "Update should succeed")
+
+			require.NoError(t, tx1.Commit(ctx), "First commit should succeed")
+			require.ErrorIs(t, tx2.Commit(ctx), dgo.ErrAborted, "Conflicting commit should be aborted")
+
+			var result TestEntity
+			require.NoError(t, client.Get(ctx, &result, entity.UID))
+			require.Equal(t, "Updated by the first transaction", result.Description)
+
+			// A transaction that read before another commit conflicts once it writes
+			tx3, err := client.Begin(ctx)
+			require.NoError(t, err, "Begin should succeed")
+			defer func() { _ = tx3.Discard(ctx) }()
+			var read TestEntity
+			require.NoError(t, tx3.Get(ctx, &read, entity.UID), "Get in transaction should succeed")
+
+			result.Description = "Updated outside the transaction"
+			require.NoError(t, client.Update(ctx, &result))
+
+			read.Description = "Updated by the third transaction"
+			require.NoError(t, tx3.Update(ctx, &read), "Update should succeed")
+			require.ErrorIs(t, tx3.Commit(ctx), dgo.ErrAborted, "Stale read-write commit should be aborted")
+
+			require.NoError(t, client.Get(ctx, &result, entity.UID))
+			require.Equal(t, "Updated outside the transaction", result.Description)
+		})
+	}
+}
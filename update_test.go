@@ -285,3 +285,158 @@ func TestClientUpdateAllTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestClientUpdateFields(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "UpdateFieldsWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "UpdateFieldsWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			entity := AllTypes{
+				Name:    "Partial Entity",
+				Age:     42,
+				Value:   3.14,
+				Bool:    true,
+				Strings: []string{"one", "two"},
+				Nodes: []*EmbeddedNodeType{
+					{Name: "First Node"},
+					{Name: "Second Node"},
+					{Name: "Third Node"},
+				},
+			}
+
+			ctx := context.Background()
+			require.NoError(t, client.Insert(ctx, &entity))
+			uid := entity.UID
+
+			// only the named fields are written, zero values included
+			partial := AllTypes{UID: uid, Name: "Ignored", Age: 0, Bool: false}
+			require.NoError(t, client.UpdateFields(ctx, &partial, "age", "Bool"))
+
+			var got AllTypes
+			require.NoError(t, client.Get(ctx, &got, uid))
+			require.Equal(t, "Partial Entity", got.Name, "Fields that are not named should not change")
+			require.Equal(t, 0, got.Age, "Zero values should be written")
+			require.False(t, got.Bool, "Zero values should be written")
+			require.Equal(t, 3.14, got.Value)
+			require.Len(t, got.Nodes, 3)
+
+			require.Error(t, client.UpdateFields(ctx, &partial, "missing"), "Unknown fields should be rejected")
+			require.Error(t, client.UpdateFields(ctx, &AllTypes{Age: 1}, "age"), "A UID is required")
+			require.Error(t, client.UpdateFields(ctx, &partial), "At least one field is required")
+
+			// edges are returned in UID order, which may differ from insertion order
+			nodeUIDs := make(map[string]string)
+			for _, node := range got.Nodes {
+				nodeUIDs[node.Name] = node.UID
+			}
+			require.NoError(t, client.RemoveEdges(ctx, uid, "nodes", nodeUIDs["First Node"], nodeUIDs["Third Node"]))
+			got = AllTypes{}
+			require.NoError(t, client.Get(ctx, &got, uid))
+			require.Len(t, got.Nodes, 1, "Only the named edges should be removed")
+			require.Equal(t, "Second Node", got.Nodes[0].Name)
+			require.Error(t, client.RemoveEdges(ctx, uid, "nodes"), "Targets are required")
+
+			require.NoError(t, client.Unset(ctx, uid, "value", "strings", "nodes"))
+			got = AllTypes{}
+			require.NoError(t, client.Get(ctx, &got, uid))
+			require.Zero(t, got.Value, "Unset attributes should be removed")
+			require.Empty(t, got.Strings, "Unset lists should be removed")
+			require.Empty(t, got.Nodes, "Unset edges should be removed")
+			require.Equal(t, "Partial Entity", got.Name)
+
+			tx, err := client.Begin(ctx)
+			require.NoError(t, err)
+			defer func() { _ = tx.Discard(ctx) }()
+			partial = AllTypes{UID: uid, Name: "Renamed in Txn"}
+			require.NoError(t, tx.UpdateFields(ctx, &partial, "name"))
+			require.NoError(t, tx.Unset(ctx, uid, "age"))
+			require.NoError(t, tx.Commit(ctx))
+
+			got = AllTypes{}
+			require.NoError(t, client.Get(ctx, &got, uid))
+			require.Equal(t, "Renamed in Txn", got.Name)
+			require.Zero(t, got.Age)
+		})
+	}
+}
+
+type TaskBase struct {
+	Owner    string `json:"owner,omitempty" dgraph:"index=exact"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+type EmbeddingTask struct {
+	TaskBase
+	Title string `json:"title,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientUpdateFieldsEmbedded(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "UpdateFieldsEmbeddedWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "UpdateFieldsEmbeddedWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			task := EmbeddingTask{TaskBase: TaskBase{Owner: "Ada", Priority: 3}, Title: "Write docs"}
+			require.NoError(t, client.Insert(ctx, &task))
+
+			// promoted fields are found by name or predicate, zero values included
+			partial := EmbeddingTask{UID: task.UID, TaskBase: TaskBase{Owner: "Grace"}, Title: "Ignored"}
+			require.NoError(t, client.UpdateFields(ctx, &partial, "owner", "Priority"))
+
+			var got EmbeddingTask
+			require.NoError(t, client.Get(ctx, &got, task.UID))
+			require.Equal(t, "Grace", got.Owner, "Promoted fields should be updated")
+			require.Zero(t, got.Priority, "Zero values of promoted fields should be written")
+			require.Equal(t, "Write docs", got.Title, "Fields that are not named should not change")
+		})
+	}
+}
@@ -7,11 +7,14 @@ package modusgraph_test
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/dgraph-io/dgo/v250/protos/api"
 	"github.com/stretchr/testify/require"
 )
 
@@ -241,3 +244,176 @@ func TestClientUpsertSlice(t *testing.T) {
 		})
 	}
 }
+
+type UpsertAccount struct {
+	TenantID   string    `json:"tenantID,omitempty" dgraph:"index=exact"`
+	ExternalID int       `json:"externalID,omitempty" dgraph:"index=int"`
+	Region     float64   `json:"region,omitempty" dgraph:"index=float"`
+	SyncedAt   time.Time `json:"syncedAt,omitzero" dgraph:"index=hour"`
+	Label      string    `json:"label,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientUpsertCompositeKey(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "UpsertCompositeKeyWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "UpsertCompositeKeyWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			syncedAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+			accounts := []*UpsertAccount{
+				{TenantID: "tenant-a", ExternalID: 42, Region: 1.5, SyncedAt: syncedAt, Label: "a-42"},
+				{TenantID: "tenant-b", ExternalID: 42, Region: 1.5, SyncedAt: syncedAt, Label: "b-42"},
+				{TenantID: "tenant-a", ExternalID: 7, Region: 1.5, SyncedAt: syncedAt, Label: "a-7"},
+				{TenantID: `tenant-"c"`, ExternalID: 42, Region: 1.5, SyncedAt: syncedAt, Label: "c-42"},
+			}
+			for _, account := range accounts {
+				require.NoError(t, client.Upsert(ctx, account, "tenantID", "externalID"), "Upsert should succeed")
+				require.NotEmpty(t, account.UID, "UID should be assigned")
+			}
+
+			updated := &UpsertAccount{TenantID: "tenant-a", ExternalID: 42, Label: "a-42 updated"}
+			require.NoError(t, client.Upsert(ctx, updated, "tenantID", "externalID"))
+			require.Equal(t, accounts[0].UID, updated.UID, "The node matching both predicates should be updated")
+
+			updated = &UpsertAccount{TenantID: `tenant-"c"`, ExternalID: 42, Label: "c-42 updated"}
+			require.NoError(t, client.Upsert(ctx, updated, "tenantID", "externalID"))
+			require.Equal(t, accounts[3].UID, updated.UID, "Values should be passed as query variables")
+
+			updated = &UpsertAccount{TenantID: "tenant-b", Region: 1.5, SyncedAt: syncedAt, Label: "b-42 updated"}
+			require.NoError(t, client.Upsert(ctx, updated, "tenantID", "region", "syncedAt"))
+			require.Equal(t, accounts[1].UID, updated.UID, "Floats and dates should be matched")
+
+			var result []UpsertAccount
+			require.NoError(t, client.Query(ctx, UpsertAccount{}).Nodes(&result))
+			require.Len(t, result, len(accounts), "Upserts should not create duplicates")
+
+			var account UpsertAccount
+			require.NoError(t, client.Get(ctx, &account, accounts[0].UID))
+			require.Equal(t, "a-42 updated", account.Label)
+			require.NoError(t, client.Get(ctx, &account, accounts[2].UID))
+			require.Equal(t, "a-7", account.Label, "Nodes matching only one predicate should not be updated")
+
+			err := client.Upsert(ctx, &UpsertAccount{TenantID: "tenant-a"}, "tenantID", "missing")
+			require.Error(t, err, "Unknown predicates should be rejected")
+		})
+	}
+}
+
+func TestClientUpsertConcurrent(t *testing.T) {
+	// the embedded engine runs each upsert block under its write lock
+	client, cleanup := CreateTestClient(t, "file://"+GetTempDir(t))
+	defer cleanup()
+
+	ctx := context.Background()
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = client.Upsert(ctx, &UpsertAccount{
+				TenantID:   "tenant-a",
+				ExternalID: 1,
+				Label:      fmt.Sprintf("worker %d", i),
+			}, "tenantID", "externalID")
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err, "Concurrent upserts should succeed")
+	}
+	var accounts []UpsertAccount
+	require.NoError(t, client.Query(ctx, UpsertAccount{}).Nodes(&accounts))
+	require.Len(t, accounts, 1, "Concurrent upserts of the same key should create a single node")
+}
+
+func TestUpsertBlock(t *testing.T) {
+	client, cleanup := CreateTestClient(t, "file://"+GetTempDir(t))
+	defer cleanup()
+
+	ctx := context.Background()
+	dgo, closeDgo, err := client.DgraphClient()
+	require.NoError(t, err)
+	defer closeDgo()
+	require.NoError(t, dgo.Alter(ctx, &api.Operation{Schema: "email: string @index(exact) .\nname: string ."}))
+
+	upsert := func(email, name string) *api.Response {
+		resp, err := dgo.NewTxn().Do(ctx, &api.Request{
+			Query: `query q($email: string) {
+				q(func: eq(email, $email)) {
+					v as uid
+					name
+				}
+			}`,
+			Vars: map[string]string{"$email": email},
+			Mutations: []*api.Mutation{
+				{
+					Cond:    "@if(eq(len(v), 0))",
+					SetJson: fmt.Appendf(nil, `{"uid": "uid(v)", "email": %q, "name": %q}`, email, name),
+				},
+				{
+					Cond:      "@if(eq(len(v), 1))",
+					SetNquads: fmt.Appendf(nil, `uid(v) <name> %q .`, name+" (updated)"),
+				},
+			},
+			CommitNow: true,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := upsert(`jane"@example.com`, "Jane")
+	require.JSONEq(t, `{"q": []}`, string(resp.GetJson()))
+	uid := resp.GetUids()["uid(v)"]
+	require.NotEmpty(t, uid, "A node should be created when the query matches none")
+
+	resp = upsert(`jane"@example.com`, "Jane")
+	require.JSONEq(t, fmt.Sprintf(`{"q": [{"uid": %q, "name": "Jane"}]}`, uid), string(resp.GetJson()),
+		"The response should hold the query result from before the mutations")
+	require.Empty(t, resp.GetUids(), "No node should be created when the query matches one")
+
+	qresp, err := dgo.NewReadOnlyTxn().Query(ctx, `{ q(func: has(email)) { uid name } }`)
+	require.NoError(t, err)
+	require.JSONEq(t, fmt.Sprintf(`{"q": [{"uid": %q, "name": "Jane (updated)"}]}`, uid), string(qresp.GetJson()),
+		"Only the mutation whose condition holds should be applied")
+
+	resp, err = dgo.NewTxn().Do(ctx, &api.Request{
+		Query:     `{ q(func: has(email)) { v as uid } }`,
+		Mutations: []*api.Mutation{{Cond: "@if(eq(len(v), 2))", SetNquads: []byte(`uid(v) <name> "Nobody" .`)}},
+		CommitNow: true,
+	})
+	require.NoError(t, err, "A request whose conditions all fail should succeed")
+	require.Empty(t, resp.GetUids())
+	qresp, err = dgo.NewReadOnlyTxn().Query(ctx, `{ q(func: has(email)) { uid name } }`)
+	require.NoError(t, err)
+	require.JSONEq(t, fmt.Sprintf(`{"q": [{"uid": %q, "name": "Jane (updated)"}]}`, uid), string(qresp.GetJson()),
+		"No mutation should be applied when no condition holds")
+}
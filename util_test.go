@@ -23,7 +23,7 @@ import (
 
 // CreateTestClient creates a new ModusGraph client for testing purposes with a configured logger.
 // It returns the client and a cleanup function that should be deferred by the caller.
-func CreateTestClient(t *testing.T, uri string) (mg.Client, func()) {
+func CreateTestClient(t *testing.T, uri string, opts ...mg.ClientOpt) (mg.Client, func()) {
 
 	stdLogger := log.New(os.Stdout, "", log.LstdFlags)
 	logger := stdr.NewWithOptions(stdLogger, stdr.Options{LogCaller: stdr.All}).WithName("mg")
@@ -39,7 +39,8 @@ func CreateTestClient(t *testing.T, uri string) (mg.Client, func()) {
 		}
 	}
 
-	client, err := mg.NewClient(uri, mg.WithAutoSchema(true), mg.WithLogger(logger))
+	opts = append([]mg.ClientOpt{mg.WithAutoSchema(true), mg.WithLogger(logger)}, opts...)
+	client, err := mg.NewClient(uri, opts...)
 	require.NoError(t, err)
 
 	cleanup := func() {
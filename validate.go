@@ -0,0 +1,312 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// validateTag is the struct tag holding the validation rules of a field, e.g.
+// `validate:"required,maxlen=100"`
+const validateTag = "validate"
+
+// fieldRule is a validation rule of a field, other than required.
+type fieldRule struct {
+	text  string                     // the rule as written in the tag, e.g. "maxlen=100"
+	check func(v reflect.Value) bool // reports whether a non-empty value passes the rule
+}
+
+// fieldValidation holds how a field of a struct type is validated.
+//
+// required: the field must not be empty.
+// rules: the other rules of the field, checked when it is not empty.
+// nested: the field may hold linked nodes, which are validated as well.
+type fieldValidation struct {
+	field    reflect.StructField
+	required bool
+	rules    []fieldRule
+	nested   bool
+}
+
+// typeValidation holds the validation of the fields of a struct type, or the error of
+// its validate tags.
+type typeValidation struct {
+	fields []fieldValidation
+	err    error
+}
+
+// typeValidations caches the validation of struct types, by type
+var typeValidations sync.Map
+
+// validationOf returns the validation of the fields of the struct type t.
+func validationOf(t reflect.Type) ([]fieldValidation, error) {
+	if cached, ok := typeValidations.Load(t); ok {
+		return cached.(*typeValidation).fields, cached.(*typeValidation).err
+	}
+	tv := &typeValidation{}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		fv := fieldValidation{field: field, nested: holdsNodes(field.Type)}
+		if tag, ok := field.Tag.Lookup(validateTag); ok {
+			if err := parseValidateTag(tag, &fv); err != nil {
+				tv.err = fmt.Errorf("invalid %s tag of %s.%s: %w", validateTag, t.Name(), field.Name, err)
+				break
+			}
+		}
+		if fv.required || len(fv.rules) > 0 || fv.nested {
+			tv.fields = append(tv.fields, fv)
+		}
+	}
+	cached, _ := typeValidations.LoadOrStore(t, tv)
+	return cached.(*typeValidation).fields, cached.(*typeValidation).err
+}
+
+// holdsNodes reports whether a field of type t can link to other nodes, that is a struct,
+// or a pointer to or a slice of structs.
+func holdsNodes(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// parseValidateTag adds the rules of a validate tag to fv. Rules are separated by commas,
+// except for pattern, which takes the rest of the tag, so it must be the last rule.
+func parseValidateTag(tag string, fv *fieldValidation) error {
+	t := fv.field.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for tag != "" {
+		var text string
+		if strings.HasPrefix(tag, "pattern=") {
+			text, tag = tag, ""
+		} else {
+			text, tag, _ = strings.Cut(tag, ",")
+			text = strings.TrimSpace(text)
+		}
+		if text == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(text, "=")
+		if name == "required" {
+			fv.required = true
+			continue
+		}
+		check, err := ruleCheck(name, value, t)
+		if err != nil {
+			return err
+		}
+		fv.rules = append(fv.rules, fieldRule{text: text, check: check})
+	}
+	return nil
+}
+
+// ruleCheck returns the check of the rule with the given name and value for values of
+// type t.
+func ruleCheck(name, value string, t reflect.Type) (func(reflect.Value) bool, error) {
+	switch name {
+	case "minlen", "maxlen":
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer: %w", name, err)
+		}
+		if !slices.Contains([]reflect.Kind{reflect.String, reflect.Slice, reflect.Array, reflect.Map}, t.Kind()) {
+			return nil, fmt.Errorf("%s only applies to strings, slices and maps", name)
+		}
+		return func(v reflect.Value) bool {
+			n := v.Len()
+			if v.Kind() == reflect.String {
+				n = utf8.RuneCountInString(v.String())
+			}
+			if name == "minlen" {
+				return n >= limit
+			}
+			return n <= limit
+		}, nil
+	case "min", "max":
+		limit, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number: %w", name, err)
+		}
+		if _, ok := numberValue(reflect.Zero(t)); !ok {
+			return nil, fmt.Errorf("%s only applies to numbers", name)
+		}
+		return func(v reflect.Value) bool {
+			n, _ := numberValue(v)
+			if name == "min" {
+				return n >= limit
+			}
+			return n <= limit
+		}, nil
+	case "pattern":
+		if t.Kind() != reflect.String {
+			return nil, fmt.Errorf("%s only applies to strings", name)
+		}
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) bool {
+			return re.MatchString(v.String())
+		}, nil
+	case "enum":
+		if _, ok := numberValue(reflect.Zero(t)); !ok && t.Kind() != reflect.String {
+			return nil, fmt.Errorf("%s only applies to strings and numbers", name)
+		}
+		allowed := strings.Split(value, "|")
+		return func(v reflect.Value) bool {
+			return slices.Contains(allowed, fmt.Sprint(v.Interface()))
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown rule %q", name)
+}
+
+// numberValue returns v as a float64, if it is a number.
+func numberValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// isEmpty reports whether v is empty for the required rule.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// validateObject checks obj, or each object of obj if it is a slice or a pointer to a
+// slice, and the nodes they link to against the rules of their validate tags. If fields
+// are given, only those fields of the objects are checked. The failures are returned as
+// a *ValidationError.
+func validateObject(obj any, fields []string) error {
+	val := reflect.ValueOf(obj)
+	if val.Kind() == reflect.Ptr && val.Elem().Kind() == reflect.Slice {
+		val = val.Elem()
+	}
+	v := &validator{seen: make(map[uintptr]bool)}
+	if val.Kind() != reflect.Slice {
+		v.index = -1
+		if err := v.validate(val, "", fields); err != nil {
+			return err
+		}
+	} else {
+		for i := range val.Len() {
+			v.index = i
+			if err := v.validate(val.Index(i), "", fields); err != nil {
+				return err
+			}
+		}
+	}
+	if len(v.failures) > 0 {
+		return &ValidationError{Fields: v.failures}
+	}
+	return nil
+}
+
+// validator collects the failures of the objects it validates.
+type validator struct {
+	failures []FieldError
+	index    int              // index of the object being validated
+	seen     map[uintptr]bool // nodes already validated, as nodes can link to each other
+}
+
+// validate checks the node val, which path leads to from the object being validated. If
+// fields are given, only those fields are checked.
+func (v *validator) validate(val reflect.Value, path string, fields []string) error {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		if val.Kind() == reflect.Ptr {
+			if v.seen[val.Pointer()] {
+				return nil
+			}
+			v.seen[val.Pointer()] = true
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	validations, err := validationOf(val.Type())
+	if err != nil {
+		return err
+	}
+	for _, fv := range validations {
+		if len(fields) > 0 && !slices.Contains(fields, fv.field.Name) &&
+			!slices.Contains(fields, predicateName(fv.field)) {
+			continue
+		}
+		value, err := val.FieldByIndexErr(fv.field.Index)
+		if err != nil {
+			// the field belongs to a nil embedded struct
+			continue
+		}
+		fieldPath := fv.field.Name
+		if path != "" {
+			fieldPath = path + "." + fv.field.Name
+		}
+		if isEmpty(value) {
+			if fv.required {
+				v.fail(fieldPath, "required", value)
+			}
+			continue
+		}
+		elem := value
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		for _, rule := range fv.rules {
+			if !rule.check(elem) {
+				v.fail(fieldPath, rule.text, elem)
+			}
+		}
+		if !fv.nested {
+			continue
+		}
+		if elem.Kind() == reflect.Slice {
+			for i := range elem.Len() {
+				if err := v.validate(elem.Index(i), fmt.Sprintf("%s[%d]", fieldPath, i), nil); err != nil {
+					return err
+				}
+			}
+		} else if err := v.validate(value, fieldPath, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fail records that the field at path breaks rule.
+func (v *validator) fail(path, rule string, value reflect.Value) {
+	v.failures = append(v.failures, FieldError{
+		Index: v.index,
+		Field: path,
+		Rule:  rule,
+		Value: value.Interface(),
+	})
+}
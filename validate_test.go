@@ -0,0 +1,144 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+type ValidatedProject struct {
+	Name   string             `json:"name,omitempty" dgraph:"index=exact upsert" validate:"required,maxlen=10"`
+	Status string             `json:"status,omitempty" validate:"enum=draft|published"`
+	Budget int                `json:"budget,omitempty" validate:"min=1,max=1000"`
+	Tasks  []*ValidatedTask   `json:"tasks,omitempty"`
+	Owner  *ValidatedTaskUser `json:"owner,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type ValidatedTask struct {
+	Title string `json:"title,omitempty" validate:"required,minlen=3"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type ValidatedTaskUser struct {
+	Email string `json:"email,omitempty" validate:"pattern=^[^@ ]+@[^@ ]+$"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type InvalidRules struct {
+	Count int `json:"count,omitempty" validate:"maxlen=3"`
+}
+
+// failedRules returns the failures of a *ValidationError as "index field rule" strings.
+func failedRules(t *testing.T, err error) []string {
+	t.Helper()
+	var validationErr *modusgraph.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	var failures []string
+	for _, field := range validationErr.Fields {
+		failures = append(failures, field.Error())
+	}
+	return failures
+}
+
+func TestClientValidation(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "ValidationWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "ValidationWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+			ctx := context.Background()
+
+			project := ValidatedProject{
+				Name:   "Apollo",
+				Status: "draft",
+				Budget: 100,
+				Tasks:  []*ValidatedTask{{Title: "Plan"}},
+				Owner:  &ValidatedTaskUser{Email: "owner@example.com"},
+			}
+			require.NoError(t, client.Insert(ctx, &project))
+
+			projects := []*ValidatedProject{
+				{Name: "Gemini"},
+				{
+					Status: "archived",
+					Budget: 5000,
+					Tasks:  []*ValidatedTask{{Title: "Build"}, {Title: "QA"}},
+					Owner:  &ValidatedTaskUser{Email: "not an email"},
+				},
+			}
+			err := client.Insert(ctx, &projects)
+			require.Equal(t, []string{
+				"[1].Name: breaks required",
+				"[1].Status: breaks enum=draft|published",
+				"[1].Budget: breaks max=1000",
+				"[1].Tasks[1].Title: breaks minlen=3",
+				"[1].Owner.Email: breaks pattern=^[^@ ]+@[^@ ]+$",
+			}, failedRules(t, err), "Every failing field of every object should be listed")
+			require.Empty(t, projects[0].UID, "No object should be inserted when one is invalid")
+
+			err = client.Upsert(ctx, &ValidatedProject{Name: "Apollo", Status: "done"})
+			require.Equal(t, []string{"Status: breaks enum=draft|published"}, failedRules(t, err))
+
+			project.Name = "Apollo Program"
+			err = client.Update(ctx, &project)
+			require.Equal(t, []string{"Name: breaks maxlen=10"}, failedRules(t, err))
+			require.NoError(t, client.UpdateFields(ctx, &project, "status"),
+				"UpdateFields should only validate the fields it writes")
+			err = client.UpdateFields(ctx, &project, "name")
+			require.Equal(t, []string{"Name: breaks maxlen=10"}, failedRules(t, err))
+
+			var fetched ValidatedProject
+			require.NoError(t, client.Get(ctx, &fetched, project.UID))
+			require.Equal(t, "Apollo", fetched.Name)
+
+			err = client.InsertBatch(ctx, []*ValidatedProject{{Name: "Mercury"}, {}, {Name: "Artemis"}},
+				modusgraph.WithBatchSize(2))
+			var batchErr *modusgraph.BatchError
+			require.ErrorAs(t, err, &batchErr)
+			require.Len(t, batchErr.Failures, 2)
+			require.Equal(t, []string{"[1].Name: breaks required"}, failedRules(t, batchErr.Failures[0]))
+
+			err = client.Insert(ctx, &InvalidRules{Count: 1})
+			require.Error(t, err)
+			require.False(t, errors.As(err, new(*modusgraph.ValidationError)),
+				"Invalid rules should be reported as such")
+		})
+	}
+}
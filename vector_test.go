@@ -9,10 +9,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/dgraph-io/dgo/v250/protos/api"
+	dg "github.com/dolan-in/dgman/v2"
 	"github.com/hypermodeinc/dgraph/v25/dgraphapi"
 	"github.com/hypermodeinc/modusgraph"
 	"github.com/stretchr/testify/require"
@@ -118,3 +120,205 @@ func queryVectors(t *testing.T, engine *modusgraph.Engine, query string) [][]flo
 	}
 	return vectors
 }
+
+type SimilarTestEntity struct {
+	Name      string            `json:"name,omitempty" dgraph:"index=exact"`
+	Embedding *dg.VectorFloat32 `json:"embedding,omitempty" dgraph:"index=hnsw(metric:\"cosine\")"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientSimilarTo(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "SimilarToWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "SimilarToWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, client.UpdateSchema(ctx, SimilarTestEntity{}))
+
+			entities := []*SimilarTestEntity{
+				{Name: "east", Embedding: &dg.VectorFloat32{Values: []float32{1, 0}}},
+				{Name: "north", Embedding: &dg.VectorFloat32{Values: []float32{0, 1}}},
+				{Name: "northeast", Embedding: &dg.VectorFloat32{Values: []float32{1, 1}}},
+			}
+			require.NoError(t, client.Insert(ctx, entities), "Insert should succeed")
+
+			query := []float32{0.9, 0.1}
+			var results []SimilarTestEntity
+			distances, err := client.SimilarTo(ctx, &results, "embedding", query, 2,
+				modusgraph.WithMetric("cosine"))
+			require.NoError(t, err, "SimilarTo should succeed")
+			require.Len(t, results, 2, "SimilarTo should return k results")
+			require.Len(t, distances, 2, "SimilarTo should return a distance per result")
+			require.Equal(t, "east", results[0].Name)
+			require.Equal(t, "northeast", results[1].Name)
+			require.Equal(t, []float32{1, 0}, results[0].Embedding.Values)
+			require.InDelta(t, 0.0061, distances[0], 0.001)
+			require.InDelta(t, 0.2191, distances[1], 0.001)
+
+			results = nil
+			distances, err = client.SimilarTo(ctx, &results, "embedding", query, 3,
+				modusgraph.WithFilter("NOT eq(name, $name)", map[string]string{"$name": "east"}))
+			require.NoError(t, err, "SimilarTo with a filter should succeed")
+			require.Len(t, results, 2, "Filtered out results should not be returned")
+			require.Equal(t, "northeast", results[0].Name)
+			require.Equal(t, "north", results[1].Name)
+			require.InDelta(t, 0.9055, distances[0], 0.001, "Euclidean distance is the default")
+
+			results = nil
+			distances, err = client.SimilarTo(ctx, &results, "embedding", query, 3,
+				modusgraph.WithMetric("dotproduct"), modusgraph.WithFirst(1))
+			require.NoError(t, err, "SimilarTo with dot product should succeed")
+			require.Len(t, results, 1)
+			require.Equal(t, "northeast", results[0].Name)
+			require.InDelta(t, 1.0, distances[0], 0.001)
+
+			_, err = client.SimilarTo(ctx, &results, "embedding", query, 1, modusgraph.WithMetric("manhattan"))
+			require.Error(t, err, "Unsupported metrics should be rejected")
+		})
+	}
+}
+
+type TaggedVectorEntity struct {
+	Name      string    `json:"name,omitempty" dgraph:"index=exact"`
+	Embedding []float32 `json:"embedding,omitempty" dgraph:"vector,index=hnsw,metric=cosine,exponent=4"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientVectorTags(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "VectorTagsWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "VectorTagsWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			// The client creates the vector predicate and its index from the struct tags
+			ctx := context.Background()
+			entities := []*TaggedVectorEntity{
+				{Name: "east", Embedding: []float32{1, 0}},
+				{Name: "north", Embedding: []float32{0, 1}},
+			}
+			require.NoError(t, client.Insert(ctx, entities), "Insert should succeed")
+
+			var got TaggedVectorEntity
+			require.NoError(t, client.Get(ctx, &got, entities[0].UID), "Get should succeed")
+			require.Equal(t, "east", got.Name)
+			require.Equal(t, []float32{1, 0}, got.Embedding, "Vector should round-trip")
+
+			var results []TaggedVectorEntity
+			distances, err := client.SimilarTo(ctx, &results, "embedding", []float32{0.1, 0.9}, 1,
+				modusgraph.WithMetric("cosine"))
+			require.NoError(t, err, "SimilarTo should use the index created from the tags")
+			require.Len(t, results, 1)
+			require.Equal(t, "north", results[0].Name)
+			require.Equal(t, []float32{0, 1}, results[0].Embedding)
+			require.InDelta(t, 0.0061, distances[0], 0.001)
+		})
+	}
+}
+
+type SchemaOwnerEntity struct {
+	Label string `json:"label,omitempty" dgraph:"index=exact"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type SchemaGuestEntity struct {
+	Label string `json:"label,omitempty" dgraph:"index=term"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+func TestClientUpdateSchemaKeepsPredicates(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "KeepPredicatesWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "KeepPredicatesWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx := context.Background()
+			require.NoError(t, client.UpdateSchema(ctx, &SchemaOwnerEntity{}))
+			require.NoError(t, client.UpdateSchema(ctx, &SchemaGuestEntity{}),
+				"A model declaring an existing predicate differently should not fail the update")
+
+			resp, err := client.QueryRaw(ctx, `schema(pred: [label]) { tokenizer }`, nil)
+			require.NoError(t, err)
+			require.JSONEq(t, `{"schema":[{"predicate":"label","tokenizer":["exact"]}]}`, string(resp),
+				"The existing predicate should keep its index")
+
+			schema, err := client.GetSchema(ctx)
+			require.NoError(t, err)
+			require.Contains(t, schema, "type SchemaGuestEntity", "The type should still be created")
+		})
+	}
+}
@@ -0,0 +1,209 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hypermodeinc/dgraph/v25/dql"
+)
+
+// defaultPollInterval is how often Watch re-runs the query of a remote client
+const defaultPollInterval = 5 * time.Second
+
+// watchOptions holds the options of Watch.
+//
+// pollInterval: how often the query is re-run for remote clients.
+type watchOptions struct {
+	pollInterval time.Duration
+}
+
+// WatchOpt is a function that configures Watch.
+type WatchOpt func(*watchOptions)
+
+// WithPollInterval sets how often Watch re-runs the query of a dgraph:// client, which has
+// no change feed to learn about commits from. It defaults to 5 seconds.
+func WithPollInterval(d time.Duration) WatchOpt {
+	return func(o *watchOptions) {
+		o.pollInterval = d
+	}
+}
+
+// Watch implements receiving the results of a query again whenever they change.
+func (c client) Watch(ctx context.Context, q string, vars map[string]string, opts ...WatchOpt) (<-chan []byte, error) {
+	var results <-chan []byte
+	err := c.intercept(ctx, "Watch", q, func(ctx context.Context) error {
+		var err error
+		results, err = c.startWatch(ctx, q, vars, opts)
+		return err
+	})
+	return results, err
+}
+
+// startWatch runs the query a first time and starts watching its results.
+func (c client) startWatch(ctx context.Context, q string, vars map[string]string,
+	opts []WatchOpt) (<-chan []byte, error) {
+	options := watchOptions{pollInterval: defaultPollInterval}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var changes <-chan Change
+	if c.isLocal() {
+		predicates, err := queryPredicates(q, vars)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		// subscribe before the first run, so that no commit is missed in between
+		if changes, err = c.ns.Subscribe(ctx, ChangeFilter{Predicates: predicates}); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	result, err := c.queryRaw(ctx, nil, q, vars)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	results := make(chan []byte)
+	go func() {
+		defer cancel()
+		defer close(results)
+		c.watch(ctx, q, vars, result, changes, options, results)
+	}()
+	return results, nil
+}
+
+// watch sends result on results, then runs the query again after every change received on
+// changes, or at the poll interval if there is no change feed, and sends the results that
+// differ from the previous one. A reader that falls behind only receives the latest result.
+func (c client) watch(ctx context.Context, q string, vars map[string]string, result []byte,
+	changes <-chan Change, options watchOptions, results chan<- []byte) {
+	var poll <-chan time.Time
+	if changes == nil {
+		ticker := time.NewTicker(options.pollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	pending := result
+	for {
+		var send chan<- []byte
+		if pending != nil {
+			send = results
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case send <- pending:
+			pending = nil
+			continue
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+			// the other changes of the commit are covered by the same run
+			for drained := false; !drained; {
+				select {
+				case _, ok := <-changes:
+					if !ok {
+						return
+					}
+				default:
+					drained = true
+				}
+			}
+		case <-poll:
+		}
+
+		latest, err := c.queryRaw(ctx, nil, q, vars)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Error(err, "Failed to re-run watched query")
+			continue
+		}
+		if !bytes.Equal(latest, result) {
+			result = latest
+			pending = latest
+		}
+	}
+}
+
+// queryPredicates returns the predicates the DQL query reads, or nil if it expands the
+// predicates of the nodes, in which case any predicate can affect its results.
+func queryPredicates(q string, vars map[string]string) ([]string, error) {
+	parsed, err := dql.Parse(dql.Request{Str: q, Variables: vars})
+	if err != nil {
+		return nil, err
+	}
+	var predicates []string
+	add := func(attr string) {
+		attr = strings.TrimPrefix(attr, "~")
+		if attr != "" && attr != "uid" && attr != "val" && !slices.Contains(predicates, attr) {
+			predicates = append(predicates, attr)
+		}
+	}
+	addFunc := func(fn *dql.Function) {
+		if fn == nil {
+			return
+		}
+		if fn.Name == "type" {
+			add("dgraph.type")
+		}
+		add(fn.Attr)
+	}
+	var addFilter func(f *dql.FilterTree)
+	addFilter = func(f *dql.FilterTree) {
+		if f == nil {
+			return
+		}
+		addFunc(f.Func)
+		for _, child := range f.Child {
+			addFilter(child)
+		}
+	}
+	var expands bool
+	var addQuery func(gq *dql.GraphQuery)
+	addQuery = func(gq *dql.GraphQuery) {
+		if gq.Expand != "" || gq.Attr == "expand" {
+			expands = true
+		}
+		if gq.Attr != "expand" {
+			add(gq.Attr)
+		}
+		addFunc(gq.Func)
+		addFilter(gq.Filter)
+		for _, order := range gq.Order {
+			add(order.Attr)
+		}
+		for _, attr := range gq.GroupbyAttrs {
+			add(attr.Attr)
+		}
+		for _, child := range gq.Children {
+			addQuery(child)
+		}
+	}
+	for _, gq := range parsed.Query {
+		addQuery(gq)
+	}
+	if expands {
+		return nil, nil
+	}
+	return predicates, nil
+}
@@ -0,0 +1,104 @@
+/*
+ * SPDX-FileCopyrightText: © Hypermode Inc. <hello@hypermode.com>
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package modusgraph_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hypermodeinc/modusgraph"
+	"github.com/stretchr/testify/require"
+)
+
+type WatchItem struct {
+	Title string `json:"title,omitempty" dgraph:"index=exact"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+type WatchOther struct {
+	Label string `json:"label,omitempty"`
+
+	UID   string   `json:"uid,omitempty"`
+	DType []string `json:"dgraph.type,omitempty"`
+}
+
+// nextResult returns the next result delivered on results, failing after a timeout.
+func nextResult(t *testing.T, results <-chan []byte) string {
+	t.Helper()
+	select {
+	case result, ok := <-results:
+		require.True(t, ok, "The watch should be open")
+		return string(result)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for a result")
+		return ""
+	}
+}
+
+func TestClientWatch(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		uri  string
+		skip bool
+	}{
+		{
+			name: "WatchWithFileURI",
+			uri:  "file://" + GetTempDir(t),
+		},
+		{
+			name: "WatchWithDgraphURI",
+			uri:  "dgraph://" + os.Getenv("MODUSGRAPH_TEST_ADDR"),
+			skip: os.Getenv("MODUSGRAPH_TEST_ADDR") == "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skipf("Skipping %s: MODUSGRAPH_TEST_ADDR not set", tc.name)
+				return
+			}
+
+			client, cleanup := CreateTestClient(t, tc.uri)
+			defer cleanup()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			require.NoError(t, client.UpdateSchema(ctx, WatchItem{}, WatchOther{}))
+
+			query := `query items($title: string) {
+				items(func: type(WatchItem), orderasc: title) @filter(NOT eq(title, $title)) { title }
+			}`
+			results, err := client.Watch(ctx, query, map[string]string{"$title": "hidden"},
+				modusgraph.WithPollInterval(50*time.Millisecond))
+			require.NoError(t, err)
+			require.JSONEq(t, `{"items":[]}`, nextResult(t, results), "The first result should be sent right away")
+
+			item := WatchItem{Title: "first"}
+			require.NoError(t, client.Insert(ctx, &item))
+			require.JSONEq(t, `{"items":[{"title":"first"}]}`, nextResult(t, results))
+
+			// commits that don't change the result are not sent
+			require.NoError(t, client.Insert(ctx, &WatchOther{Label: "unrelated"}))
+			require.NoError(t, client.Insert(ctx, &WatchItem{Title: "hidden"}))
+			item.Title = "renamed"
+			require.NoError(t, client.Update(ctx, &item))
+			require.JSONEq(t, `{"items":[{"title":"renamed"}]}`, nextResult(t, results))
+
+			require.NoError(t, client.Delete(ctx, []string{item.UID}))
+			require.JSONEq(t, `{"items":[]}`, nextResult(t, results))
+
+			cancel()
+			for range results {
+			}
+		})
+	}
+}
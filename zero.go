@@ -7,6 +7,8 @@ package modusgraph
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/hypermodeinc/dgraph/v25/posting"
@@ -43,6 +45,8 @@ type zero struct {
 	maxLeasedTs uint64
 
 	lastNamespace uint64
+	// IDs of deleted namespaces, which are never handed out again
+	deletedNamespaces map[uint64]struct{}
 }
 
 func newZero() (*zero, bool, error) {
@@ -52,7 +56,7 @@ func newZero() (*zero, bool, error) {
 	}
 	restart := zs != nil
 
-	z := &zero{}
+	z := &zero{deletedNamespaces: make(map[uint64]struct{})}
 	if zs == nil {
 		z.minLeasedUID = initialUID
 		z.maxLeasedUID = initialUID
@@ -65,6 +69,9 @@ func newZero() (*zero, bool, error) {
 		z.minLeasedTs = zs.MaxTxnTs
 		z.maxLeasedTs = zs.MaxTxnTs
 		z.lastNamespace = zs.MaxNsID
+		for _, m := range zs.Removed {
+			z.deletedNamespaces[m.Id] = struct{}{}
+		}
 	}
 	posting.Oracle().ProcessDelta(&pb.OracleDelta{MaxAssigned: z.minLeasedTs - 1})
 	worker.SetMaxUID(z.minLeasedUID - 1)
@@ -129,6 +136,22 @@ func (z *zero) nextNamespace() (uint64, error) {
 	return z.lastNamespace, nil
 }
 
+// deleteNamespace tombstones the namespace ID and persists it in the zero state.
+func (z *zero) deleteNamespace(nsID uint64) error {
+	z.deletedNamespaces[nsID] = struct{}{}
+	if err := z.writeZeroState(); err != nil {
+		delete(z.deletedNamespaces, nsID)
+		return fmt.Errorf("error deleting namespace ID: %w", err)
+	}
+	return nil
+}
+
+// isDeletedNamespace reports whether the namespace ID has been deleted.
+func (z *zero) isDeletedNamespace(nsID uint64) bool {
+	_, ok := z.deletedNamespaces[nsID]
+	return ok
+}
+
 func readZeroState() (*pb.MembershipState, error) {
 	txn := worker.State.Pstore.NewTransactionAt(zeroStateTs, false)
 	defer txn.Discard()
@@ -154,6 +177,10 @@ func readZeroState() (*pb.MembershipState, error) {
 
 func (z *zero) writeZeroState() error {
 	zeroState := &pb.MembershipState{MaxUID: z.maxLeasedUID, MaxTxnTs: z.maxLeasedTs, MaxNsID: z.lastNamespace}
+	// deleted namespaces are recorded as removed members, identified by the namespace ID
+	for _, nsID := range slices.Sorted(maps.Keys(z.deletedNamespaces)) {
+		zeroState.Removed = append(zeroState.Removed, &pb.Member{Id: nsID})
+	}
 	data, err := proto.Marshal(zeroState)
 	if err != nil {
 		return fmt.Errorf("error marshalling zero state: %w", err)